@@ -0,0 +1,165 @@
+package grok
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// AdminSettingsPath serves the effective settings with secrets redacted
+	AdminSettingsPath = "/settings"
+	// AdminRoutesPath serves the routes registered on the public router
+	AdminRoutesPath = "/routes"
+
+	redacted = "[REDACTED]"
+)
+
+// sensitiveSettings are settings keys, or key suffixes, whose values are
+// always redacted
+var sensitiveSettings = []string{
+	"connection_string",
+	"key",
+	"passphrase",
+	"password",
+	"secret",
+	"token",
+}
+
+// AdminRoute ...
+type AdminRoute struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// newAdminEngine mounts the operational endpoints: healthz, metrics, pprof,
+// the redacted settings and the public route table.
+func (server *API) newAdminEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+
+	if server.healthz != nil {
+		engine.GET("/healthz", server.readiness(), server.healthz)
+	}
+
+	if server.metrics != nil {
+		engine.GET(MetricsPath, server.metrics.Handler())
+	}
+
+	debug := engine.Group("/debug/pprof")
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+	debug.GET("/:profile", func(c *gin.Context) {
+		pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+	})
+
+	engine.GET(AdminSettingsPath, func(c *gin.Context) {
+		current := server.currentSettings()
+		settings, err := RedactSettings(current, restrictedFields(current))
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, settings)
+	})
+
+	engine.GET(AdminRoutesPath, func(c *gin.Context) {
+		routes := []AdminRoute{}
+		for _, route := range server.Engine.Routes() {
+			routes = append(routes, AdminRoute{
+				Method:  route.Method,
+				Path:    route.Path,
+				Handler: route.Handler,
+			})
+		}
+		c.JSON(http.StatusOK, routes)
+	})
+
+	return engine
+}
+
+func (server *API) runAdmin(srv *http.Server, errCh chan<- error) {
+	if srv == nil {
+		return
+	}
+	go func() {
+		logrus.Infof("start admin %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("admin startup error: %v", err)
+		}
+	}()
+}
+
+// RedactSettings converts settings into a generic map, replacing the values
+// of sensitive keys and of the given restricted keys.
+func RedactSettings(settings interface{}, restricteds []string) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	keys := append(append([]string{}, sensitiveSettings...), restricteds...)
+
+	result, _ := redactValue(raw, keys).(map[string]interface{})
+	return result, nil
+}
+
+func redactValue(value interface{}, keys []string) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range v {
+			name := fmt.Sprint(key)
+			if isSensitiveSetting(name, keys) && isLeaf(item) {
+				result[name] = redacted
+				continue
+			}
+			result[name] = redactValue(item, keys)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item, keys)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+func isSensitiveSetting(name string, keys []string) bool {
+	name = strings.ToLower(name)
+	for _, key := range keys {
+		key = strings.ToLower(key)
+		if key != "" && (name == key || strings.HasSuffix(name, "_"+key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLeaf reports whether value holds data rather than a nested section
+func isLeaf(value interface{}) bool {
+	switch value.(type) {
+	case nil, map[interface{}]interface{}:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,495 @@
+package grok
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// APIKeyHeader carries the api key, as an alternative to an
+	// "ApiKey <key>" authorization
+	APIKeyHeader = "X-API-Key"
+	// APIKeyScheme ...
+	APIKeyScheme = "ApiKey"
+
+	defaultAPIKeyPrefix        = "gk"
+	defaultAPIKeyCollection    = "api_keys"
+	defaultAPIKeyCacheTTL      = time.Minute
+	defaultAPIKeyTouchInterval = time.Minute
+	apiKeyTouchTimeout         = 5 * time.Second
+	apiKeyNegativeCacheTTL     = 5 * time.Second
+)
+
+var (
+	// ErrAPIKeyExpired ...
+	ErrAPIKeyExpired = NewError(http.StatusUnauthorized, "API_KEY_EXPIRED", "api key expired")
+	// ErrAPIKeyRevoked ...
+	ErrAPIKeyRevoked = NewError(http.StatusUnauthorized, "API_KEY_REVOKED", "api key revoked")
+)
+
+// APIKeySettings ...
+type APIKeySettings struct {
+	Fake          bool   `yaml:"fake"`
+	Collection    string `yaml:"collection"`                      // default api_keys
+	CacheTTL      int64  `yaml:"cache_ttl" validate:"gte=0"`      // seconds, default 60, revocations take up to it to apply
+	TouchInterval int64  `yaml:"touch_interval" validate:"gte=0"` // seconds, default 60, last_used_at precision
+}
+
+// APIKey is a long-lived credential of a machine-to-machine client. Only
+// the key hash is stored, the plain key is returned once by IssueAPIKey.
+type APIKey struct {
+	ID         string     `bson:"_id" json:"id"`
+	Name       string     `bson:"name" json:"name"`
+	Prefix     string     `bson:"prefix" json:"prefix"` // public part of the key, to identify it
+	Hash       string     `bson:"hash" json:"-"`
+	Identity   string     `bson:"identity" json:"identity"` // owning identity, set as the sub claim
+	Scopes     []string   `bson:"scopes" json:"scopes"`
+	Stores     []string   `bson:"stores" json:"stores"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Claims returns the key as token claims, with the same keys setKeys sets
+// for bearer tokens
+func (k *APIKey) Claims() map[string]interface{} {
+	claims := map[string]interface{}{
+		"jti":                         k.ID,
+		"sub":                         k.Identity,
+		"iat":                         k.CreatedAt.Unix(),
+		"permissions":                 interfaces(k.Scopes),
+		AuthClaimNamespace + "stores": interfaces(k.Stores),
+	}
+
+	if k.ExpiresAt != nil {
+		claims["exp"] = k.ExpiresAt.Unix()
+	}
+
+	return claims
+}
+
+// APIKeyStore keeps api keys by id and hash
+type APIKeyStore interface {
+	Create(ctx context.Context, key *APIKey) error
+	// FindByHash returns nil when there is no key with hash
+	FindByHash(ctx context.Context, hash string) (*APIKey, error)
+	FindByIdentity(ctx context.Context, identity string) ([]*APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	Touch(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// CreateAPIKeyStore creates a mongo store, or an in-memory one when fake
+func CreateAPIKeyStore(settings *Settings) APIKeyStore {
+	s := settings.APIKeys
+	if s == nil {
+		s = &APIKeySettings{}
+	}
+
+	if s.Fake {
+		return NewMemoryAPIKeyStore()
+	}
+
+	collection := s.Collection
+	if collection == "" {
+		collection = defaultAPIKeyCollection
+	}
+
+	client := NewMongoConnection(settings.Mongo.ConnectionString, settings.Mongo.CaFilePath)
+
+	return NewMongoAPIKeyStore(client.Database(settings.Mongo.Database).Collection(collection))
+}
+
+// IssueAPIKey generates a key for the identity, scopes and stores of key,
+// saves it and returns the plain key, which cannot be recovered later
+func IssueAPIKey(ctx context.Context, store APIKeyStore, prefix string, key *APIKey) (string, error) {
+	if prefix == "" {
+		prefix = defaultAPIKeyPrefix
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	plain := prefix + "_" + id + "_" + secret
+
+	key.ID = id
+	key.Prefix = prefix + "_" + id
+	key.Hash = tokenCacheKey(plain)
+	key.CreatedAt = time.Now().UTC()
+
+	if err := store.Create(ctx, key); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// APIKeyAuthenticate authenticates requests with the api keys of store,
+// given as "Authorization: ApiKey <key>" or in the X-API-Key header
+type APIKeyAuthenticate struct {
+	store         APIKeyStore
+	memoryCache   *cache.Cache
+	cacheTTL      time.Duration
+	touchInterval time.Duration
+	options       *authenticateOptions
+
+	mutex   sync.Mutex
+	touched map[string]time.Time
+	pruned  time.Time
+}
+
+// CreateAPIKeyAuthenticate ...
+func CreateAPIKeyAuthenticate(settings *Settings, cache *cache.Cache, opts ...AuthenticateOption) Authenticate {
+	return NewAPIKeyAuthenticate(settings.APIKeys, CreateAPIKeyStore(settings), cache, opts...)
+}
+
+// NewAPIKeyAuthenticate ...
+func NewAPIKeyAuthenticate(settings *APIKeySettings, store APIKeyStore, cache *cache.Cache, opts ...AuthenticateOption) *APIKeyAuthenticate {
+	a := &APIKeyAuthenticate{
+		store:         store,
+		memoryCache:   cache,
+		cacheTTL:      defaultAPIKeyCacheTTL,
+		touchInterval: defaultAPIKeyTouchInterval,
+		options:       newAuthenticateOptions(opts),
+		touched:       map[string]time.Time{},
+	}
+
+	if settings != nil {
+		if settings.CacheTTL > 0 {
+			a.cacheTTL = time.Duration(settings.CacheTTL) * time.Second
+		}
+		if settings.TouchInterval > 0 {
+			a.touchInterval = time.Duration(settings.TouchInterval) * time.Second
+		}
+	}
+
+	return a
+}
+
+// Middleware ...
+func (a *APIKeyAuthenticate) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorization := c.Request.Header.Get("authorization")
+		if key := c.Request.Header.Get(APIKeyHeader); key != "" {
+			authorization = APIKeyScheme + " " + key
+		}
+
+		claims, err := a.ValidateToken(c.Request.Context(), authorization)
+
+		if err != nil {
+			c.Error(err)
+			abortWithStatus(c, http.StatusUnauthorized)
+			return
+		}
+
+		setKeys(c, claims)
+
+		c.Next()
+	}
+}
+
+// ValidateToken validates an "ApiKey <key>" authorization and returns the
+// key claims
+func (a *APIKeyAuthenticate) ValidateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	plain, err := apiKey(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.find(ctx, tokenCacheKey(plain))
+	if err != nil {
+		return nil, err
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	claims := key.Claims()
+
+	if err := a.options.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	a.touch(key.ID)
+
+	return claims, nil
+}
+
+// find returns the cached key of hash or loads it from the store
+func (a *APIKeyAuthenticate) find(ctx context.Context, hash string) (*APIKey, error) {
+	cacheKey := "api_key:" + hash
+
+	if a.memoryCache != nil {
+		if value, found := a.memoryCache.Get(cacheKey); found {
+			if key := value.(*APIKey); key != nil {
+				return key, nil
+			}
+			return nil, ErrUnauthorized
+		}
+	}
+
+	key, err := a.store.FindByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		// unknown keys are cached briefly so guessing keys does not hit the
+		// store on every request
+		if a.memoryCache != nil {
+			a.memoryCache.Set(cacheKey, key, apiKeyNegativeCacheTTL)
+		}
+		return nil, ErrUnauthorized
+	}
+
+	if a.memoryCache != nil {
+		a.memoryCache.Set(cacheKey, key, a.cacheTTL)
+	}
+
+	return key, nil
+}
+
+// touch records the key usage in background, at most once per touch
+// interval
+func (a *APIKeyAuthenticate) touch(id string) {
+	now := time.Now().UTC()
+
+	a.mutex.Lock()
+	a.prune(now)
+	if last, found := a.touched[id]; found && now.Sub(last) < a.touchInterval {
+		a.mutex.Unlock()
+		return
+	}
+	a.touched[id] = now
+	a.mutex.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), apiKeyTouchTimeout)
+		defer cancel()
+
+		if err := a.store.Touch(ctx, id, now); err != nil {
+			logrus.WithError(err).
+				WithField("api_key", id).
+				Error("error updating api key last use")
+		}
+	}()
+}
+
+// prune forgets the keys touched more than a touch interval ago, at most
+// once per interval. It must be called holding the mutex.
+func (a *APIKeyAuthenticate) prune(now time.Time) {
+	if now.Sub(a.pruned) < a.touchInterval {
+		return
+	}
+
+	for id, last := range a.touched {
+		if now.Sub(last) >= a.touchInterval {
+			delete(a.touched, id)
+		}
+	}
+	a.pruned = now
+}
+
+// MongoAPIKeyStore ...
+type MongoAPIKeyStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAPIKeyStore creates the hash and identity indexes of collection
+func NewMongoAPIKeyStore(collection *mongo.Collection) *MongoAPIKeyStore {
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.M{"hash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"identity": 1}},
+	})
+
+	if err != nil {
+		logrus.WithError(err).Error("error creating api keys indexes")
+	}
+
+	return &MongoAPIKeyStore{collection: collection}
+}
+
+// Create ...
+func (s *MongoAPIKeyStore) Create(ctx context.Context, key *APIKey) error {
+	_, err := s.collection.InsertOne(ctx, key)
+	return err
+}
+
+// FindByHash ...
+func (s *MongoAPIKeyStore) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	key := new(APIKey)
+
+	err := s.collection.FindOne(ctx, bson.M{"hash": hash}).Decode(key)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// FindByIdentity ...
+func (s *MongoAPIKeyStore) FindByIdentity(ctx context.Context, identity string) ([]*APIKey, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"identity": identity},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke ...
+func (s *MongoAPIKeyStore) Revoke(ctx context.Context, id string) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}})
+	return err
+}
+
+// Touch ...
+func (s *MongoAPIKeyStore) Touch(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$max": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+// MemoryAPIKeyStore keeps the api keys in process. It is meant for tests.
+type MemoryAPIKeyStore struct {
+	mutex sync.RWMutex
+	keys  map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore ...
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: map[string]*APIKey{}}
+}
+
+// Create ...
+func (s *MemoryAPIKeyStore) Create(ctx context.Context, key *APIKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	copied := *key
+	s.keys[key.ID] = &copied
+
+	return nil
+}
+
+// FindByHash ...
+func (s *MemoryAPIKeyStore) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// FindByIdentity ...
+func (s *MemoryAPIKeyStore) FindByIdentity(ctx context.Context, identity string) ([]*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := []*APIKey{}
+	for _, key := range s.keys {
+		if key.Identity == identity {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// Revoke ...
+func (s *MemoryAPIKeyStore) Revoke(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if key, found := s.keys[id]; found && key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+	}
+
+	return nil
+}
+
+// Touch ...
+func (s *MemoryAPIKeyStore) Touch(ctx context.Context, id string, usedAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if key, found := s.keys[id]; found && (key.LastUsedAt == nil || usedAt.After(*key.LastUsedAt)) {
+		key.LastUsedAt = &usedAt
+	}
+
+	return nil
+}
+
+// apiKey extracts the key of an "ApiKey <key>" authorization
+func apiKey(authorization string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(authorization), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], APIKeyScheme) || strings.TrimSpace(parts[1]) == "" {
+		return "", ErrUnauthorized
+	}
+	return strings.TrimSpace(parts[1]), nil
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func interfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
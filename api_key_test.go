@@ -0,0 +1,156 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueAPIKey(t *testing.T, store grok.APIKeyStore, key *grok.APIKey) string {
+	plain, err := grok.IssueAPIKey(context.Background(), store, "", key)
+	assert.NoError(t, err)
+	return plain
+}
+
+type countingAPIKeyStore struct {
+	grok.APIKeyStore
+	finds int32
+}
+
+func (s *countingAPIKeyStore) FindByHash(ctx context.Context, hash string) (*grok.APIKey, error) {
+	atomic.AddInt32(&s.finds, 1)
+	return s.APIKeyStore.FindByHash(ctx, hash)
+}
+
+func TestIssueAPIKey(t *testing.T) {
+	store := grok.NewMemoryAPIKeyStore()
+
+	plain, err := grok.IssueAPIKey(context.Background(), store, "partner", &grok.APIKey{Identity: "partner|1"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "partner_"))
+
+	keys, err := store.FindByIdentity(context.Background(), "partner|1")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.True(t, strings.HasPrefix(plain, keys[0].Prefix+"_"))
+	assert.NotContains(t, keys[0].Hash, plain)
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	store := grok.CreateAPIKeyStore(&grok.Settings{APIKeys: &grok.APIKeySettings{Fake: true}})
+	plain := issueAPIKey(t, store, &grok.APIKey{
+		Identity: "partner|1",
+		Scopes:   []string{"read:accounts", "write:transfers"},
+		Stores:   []string{"store-1"},
+	})
+
+	authenticate := grok.NewAPIKeyAuthenticate(nil, store, cache.New(time.Minute, time.Minute))
+
+	engine := gin.New()
+	engine.GET("/stores/:store_id/transfers",
+		authenticate.Middleware(),
+		grok.TokenScopesRequired([]string{"read:accounts", "write:transfers"}),
+		grok.EnsureStoreFromPath("store_id"),
+		func(c *gin.Context) { c.String(http.StatusOK, c.GetString("sub")) })
+
+	request := func(path string, header string, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/stores/store-1/transfers", "Authorization", "ApiKey "+plain)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partner|1", w.Body.String())
+
+	assert.Equal(t, http.StatusOK, request("/stores/store-1/transfers", grok.APIKeyHeader, plain).Code)
+	assert.Equal(t, http.StatusForbidden, request("/stores/store-2/transfers", grok.APIKeyHeader, plain).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/stores/store-1/transfers", grok.APIKeyHeader, plain+"x").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/stores/store-1/transfers", "Authorization", "Bearer "+plain).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/stores/store-1/transfers", "", "").Code)
+
+	assert.Eventually(t, func() bool {
+		keys, _ := store.FindByIdentity(context.Background(), "partner|1")
+		return keys[0].LastUsedAt != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAPIKeyAuthenticateExpiredAndRevoked(t *testing.T) {
+	store := grok.NewMemoryAPIKeyStore()
+	expiresAt := time.Now().Add(-time.Minute)
+
+	expired := issueAPIKey(t, store, &grok.APIKey{Identity: "partner|1", ExpiresAt: &expiresAt})
+	revoked := issueAPIKey(t, store, &grok.APIKey{Identity: "partner|2"})
+
+	keys, _ := store.FindByIdentity(context.Background(), "partner|2")
+	assert.NoError(t, store.Revoke(context.Background(), keys[0].ID))
+
+	authenticate := grok.NewAPIKeyAuthenticate(nil, store, nil)
+
+	_, err := authenticate.ValidateToken(context.Background(), "ApiKey "+expired)
+	assert.Equal(t, grok.ErrAPIKeyExpired, err)
+
+	_, err = authenticate.ValidateToken(context.Background(), "ApiKey "+revoked)
+	assert.Equal(t, grok.ErrAPIKeyRevoked, err)
+}
+
+func TestAPIKeyAuthenticateRevocationStore(t *testing.T) {
+	store := grok.NewMemoryAPIKeyStore()
+	plain := issueAPIKey(t, store, &grok.APIKey{Identity: "partner|1"})
+
+	revocation := grok.NewMemoryRevocationStore()
+	authenticate := grok.NewAPIKeyAuthenticate(nil, store, nil, grok.WithRevocationStore(revocation))
+
+	claims, err := authenticate.ValidateToken(context.Background(), "ApiKey "+plain)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{}, claims["permissions"])
+
+	assert.NoError(t, revocation.RevokeSubject(context.Background(), "partner|1", time.Now()))
+
+	_, err = authenticate.ValidateToken(context.Background(), "ApiKey "+plain)
+	assert.Equal(t, grok.ErrTokenRevoked, err)
+}
+
+func TestAPIKeyAuthenticateNegativeCache(t *testing.T) {
+	store := &countingAPIKeyStore{APIKeyStore: grok.NewMemoryAPIKeyStore()}
+	authenticate := grok.NewAPIKeyAuthenticate(nil, store, cache.New(time.Minute, time.Minute))
+
+	for i := 0; i < 3; i++ {
+		_, err := authenticate.ValidateToken(context.Background(), "ApiKey gk_unknown")
+		assert.Equal(t, grok.ErrUnauthorized, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&store.finds))
+}
+
+func TestAPIKeyAuthenticatePrunesTouched(t *testing.T) {
+	store := grok.NewMemoryAPIKeyStore()
+	first := issueAPIKey(t, store, &grok.APIKey{Identity: "partner|1"})
+	second := issueAPIKey(t, store, &grok.APIKey{Identity: "partner|2"})
+
+	authenticate := grok.NewAPIKeyAuthenticate(nil, store, nil)
+	authenticate.SetTouchInterval(50 * time.Millisecond)
+
+	_, err := authenticate.ValidateToken(context.Background(), "ApiKey "+first)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, authenticate.Touched())
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = authenticate.ValidateToken(context.Background(), "ApiKey "+second)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, authenticate.Touched())
+}
@@ -2,6 +2,7 @@ package grok
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -18,20 +19,58 @@ const (
 	AuthClaimNamespace = "https://api.contbank.com/"
 )
 
+var (
+	// ErrUnauthorized ...
+	ErrUnauthorized = NewError(http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	// ErrForbidden ...
+	ErrForbidden = NewError(http.StatusForbidden, "FORBIDDEN", "forbidden")
+)
+
 // Authenticate ...
 type Authenticate interface {
 	Middleware() gin.HandlerFunc
 }
 
+// TokenValidator validates an authorization outside of a gin request, e.g.
+// in the grpc interceptors. Every grok Authenticate implements it.
+type TokenValidator interface {
+	// ValidateToken validates the authorization header value and returns its claims
+	ValidateToken(ctx context.Context, authorization string) (map[string]interface{}, error)
+}
+
 // Auth0Authenticate ...
 type Auth0Authenticate struct {
 	memoryCache    *cache.Cache
 	auth           *APIAuth
 	auth0Validator *auth0.JWTValidator
+	options        *authenticateOptions
+}
+
+// AuthenticateOption ...
+type AuthenticateOption func(*authenticateOptions)
+
+type authenticateOptions struct {
+	revocation RevocationStore
+}
+
+// WithRevocationStore rejects the tokens revoked in store on every request,
+// including the ones with cached claims
+func WithRevocationStore(store RevocationStore) AuthenticateOption {
+	return func(o *authenticateOptions) {
+		o.revocation = store
+	}
+}
+
+func newAuthenticateOptions(opts []AuthenticateOption) *authenticateOptions {
+	options := &authenticateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
 }
 
 // CreateAuthenticate ...
-func CreateAuthenticate(auth *APIAuth, cache *cache.Cache) Authenticate {
+func CreateAuthenticate(auth *APIAuth, cache *cache.Cache, opts ...AuthenticateOption) Authenticate {
 	if auth.Fake {
 		return NewFakeAuthenticate(
 			auth.FakeConfig.Authenticated,
@@ -39,12 +78,16 @@ func CreateAuthenticate(auth *APIAuth, cache *cache.Cache) Authenticate {
 		)
 	}
 
-	return NewAuthenticate(auth, cache)
+	if auth.Provider == OIDCProvider {
+		return NewOIDCAuthenticate(auth, cache, opts...)
+	}
+
+	return NewAuthenticate(auth, cache, opts...)
 }
 
 // NewAuthenticate ...
-func NewAuthenticate(auth *APIAuth, cache *cache.Cache) Authenticate {
-	a := &Auth0Authenticate{auth: auth, memoryCache: cache}
+func NewAuthenticate(auth *APIAuth, cache *cache.Cache, opts ...AuthenticateOption) Authenticate {
+	a := &Auth0Authenticate{auth: auth, memoryCache: cache, options: newAuthenticateOptions(opts)}
 
 	a.auth0Validator = auth0.NewValidator(
 		auth0.NewConfiguration(
@@ -66,45 +109,91 @@ func NewAuthenticate(auth *APIAuth, cache *cache.Cache) Authenticate {
 // Middleware ...
 func (a *Auth0Authenticate) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		jwt := c.Request.Header.Get("authorization")
-
-		if claims, found := a.memoryCache.Get(jwt); found {
-			a.setKeys(c, claims.(map[string]interface{}))
-			c.Next()
-			return
-		}
-
-		token, err := a.auth0Validator.ValidateRequest(c.Request)
+		claims, err := a.ValidateToken(c.Request.Context(), c.Request.Header.Get("authorization"))
 
 		if err != nil {
 			c.Error(err)
-			c.AbortWithStatus(http.StatusUnauthorized)
+			abortWithStatus(c, http.StatusUnauthorized)
 			return
 		}
 
-		claims := make(map[string]interface{})
-		if err := a.auth0Validator.Claims(c.Request, token, &claims); err != nil {
-			c.Error(err)
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
+		setKeys(c, claims)
+
+		c.Next()
+	}
+}
+
+// ValidateToken ...
+func (a *Auth0Authenticate) ValidateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	claims, err := a.validateToken(ctx, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.options.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// validateToken returns the cached claims of authorization or validates it,
+// caching the claims until the token expires
+func (a *Auth0Authenticate) validateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	key := tokenCacheKey(authorization)
+
+	if claims, found := a.memoryCache.Get(key); found {
+		return claims.(map[string]interface{}), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
 
-		a.setKeys(c, claims)
+	token, err := a.auth0Validator.ValidateRequest(req)
 
-		if exp, ok := claims["exp"]; ok {
-			float := exp.(float64)
-			a.memoryCache.Set(jwt, claims, time.Second*time.Duration(int64(float)))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if err := a.auth0Validator.Claims(req, token, &claims); err != nil {
+		return nil, err
+	}
+
+	if exp, ok := numericClaim(claims, "exp"); ok {
+		if ttl := time.Until(time.Unix(exp, 0)); ttl > 0 {
+			a.memoryCache.Set(key, claims, ttl)
 		}
+	}
 
-		c.Next()
+	return claims, nil
+}
+
+// checkRevoked fails closed, a token is rejected when the revocation store
+// cannot be checked
+func (o *authenticateOptions) checkRevoked(ctx context.Context, claims map[string]interface{}) error {
+	if o.revocation == nil {
+		return nil
+	}
+
+	revoked, err := o.revocation.IsRevoked(ctx, claims)
+	if err != nil {
+		return fmt.Errorf("error checking token revocation: %v", err)
 	}
+
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
 }
 
-func (a *Auth0Authenticate) setKeys(ctx *gin.Context, claims map[string]interface{}) {
+func setKeys(ctx *gin.Context, claims map[string]interface{}) {
 	for key, value := range claims {
-		if strings.Index(key, AuthClaimNamespace) >= 0 {
-			key = strings.Replace(key, AuthClaimNamespace, "", -1)
-		}
+		key = claimKey(key)
 
 		ctx.Set(key, value)
 
@@ -112,6 +201,23 @@ func (a *Auth0Authenticate) setKeys(ctx *gin.Context, claims map[string]interfac
 	}
 }
 
+// claimKey removes AuthClaimNamespace from custom claims
+func claimKey(key string) string {
+	if strings.Index(key, AuthClaimNamespace) >= 0 {
+		key = strings.Replace(key, AuthClaimNamespace, "", -1)
+	}
+	return key
+}
+
+// ContextWithClaims adds claims to ctx the same way the HTTP middleware adds
+// them to the request context
+func ContextWithClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	for key, value := range claims {
+		ctx = context.WithValue(ctx, interface{}(claimKey(key)), value)
+	}
+	return ctx
+}
+
 // parseSub removes {{provider}}| prefix that Auth0 uses
 func parseSub(value string) string {
 	splited := strings.Split(value, "|")
@@ -128,7 +234,7 @@ func RequiredClaims(claims ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		for _, c := range claims {
 			if _, exists := ctx.Get(c); !exists {
-				ctx.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(ctx, http.StatusForbidden)
 				return
 			}
 		}
@@ -142,7 +248,7 @@ func RequiredHeaders(headers ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		for _, c := range headers {
 			if content := ctx.GetHeader(c); content == "" {
-				ctx.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(ctx, http.StatusForbidden)
 				return
 			}
 		}
@@ -1,6 +1,7 @@
 package grok
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,11 +21,20 @@ func NewFakeAuthenticate(authenticated bool, claims map[string]interface{}) Auth
 	}
 }
 
+// ValidateToken ...
+func (a *FakeAuthenticate) ValidateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	if !a.authenticated {
+		return nil, ErrUnauthorized
+	}
+
+	return a.claims, nil
+}
+
 // Middleware ...
 func (a *FakeAuthenticate) Middleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if !a.authenticated {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			abortWithStatus(ctx, http.StatusUnauthorized)
 		}
 
 		for k, v := range a.claims {
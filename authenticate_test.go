@@ -0,0 +1,68 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/contbank/grok/groktest"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateLocalIssuer(t *testing.T) {
+	issuer := newLocalIssuer(t)
+	authenticate := grok.NewAuthenticate(issuer.APIAuth(), cache.New(time.Minute, time.Minute))
+
+	engine := gin.New()
+	engine.GET("/customers", authenticate.Middleware(), grok.TokenScopeRequired("read:customers"),
+		func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"sub": c.GetString("sub"), "stores": c.Request.Context().Value("stores")})
+		})
+
+	request := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/customers", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(authorization(t, issuer,
+		groktest.WithSubject("auth0|123"),
+		groktest.WithPermissions("read:customers"),
+		groktest.WithStores("store")))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"sub": "auth0|123", "stores": ["store"]}`, w.Body.String())
+
+	w = request(authorization(t, issuer, groktest.WithPermissions("write:customers")))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = request(authorization(t, issuer, groktest.WithPermissions("read:customers"), groktest.WithExpiry(-time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = request(authorization(t, issuer, groktest.WithPermissions("read:customers"), groktest.WithAudience("https://other.com")))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	other := newLocalIssuer(t)
+	w = request(authorization(t, other, groktest.WithPermissions("read:customers"), groktest.WithClaim("iss", issuer.Issuer())))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticateValidateToken(t *testing.T) {
+	issuer := newLocalIssuer(t)
+	authenticate := grok.NewAuthenticate(issuer.APIAuth(), cache.New(time.Minute, time.Minute)).(grok.TokenValidator)
+
+	claims, err := authenticate.ValidateToken(context.Background(), authorization(t, issuer, groktest.WithSubject("auth0|123")))
+	assert.NoError(t, err)
+	assert.Equal(t, "auth0|123", claims["sub"])
+	assert.Equal(t, issuer.Issuer(), claims["iss"])
+
+	_, err = authenticate.ValidateToken(context.Background(), "Bearer token")
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package grok
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -23,7 +24,7 @@ func Authorize(scope string) gin.HandlerFunc {
 		permissions, exists := c.Get("permissions")
 
 		if !exists {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 
@@ -34,7 +35,7 @@ func Authorize(scope string) gin.HandlerFunc {
 			}
 		}
 
-		c.AbortWithStatus(http.StatusForbidden)
+		abortWithStatus(c, http.StatusForbidden)
 	}
 }
 
@@ -49,7 +50,7 @@ func TokenScopesRequired(scopes []string) gin.HandlerFunc {
 		permissions, exists := c.Get("permissions")
 
 		if !exists {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 
@@ -74,7 +75,7 @@ func TokenScopesRequired(scopes []string) gin.HandlerFunc {
 			return
 		}
 
-		c.AbortWithStatus(http.StatusForbidden)
+		abortWithStatus(c, http.StatusForbidden)
 	}
 }
 
@@ -83,6 +84,13 @@ type InternalAuthorize interface {
 	PermissionsRequired(scopes []string) gin.HandlerFunc
 }
 
+// PermissionVerifier checks permissions outside of a gin request, e.g. in
+// the grpc interceptors. Every grok InternalAuthorize implements it.
+type PermissionVerifier interface {
+	// VerifyPermissions checks scopes for the identity
+	VerifyPermissions(ctx context.Context, authorization string, currentIdentity string, scopes []string) error
+}
+
 type APIAuthorize struct {
 	settings *InternalAuth
 }
@@ -115,7 +123,7 @@ func (a *APIAuthorize) PermissionRequired(scope string) gin.HandlerFunc {
 func (a *APIAuthorize) PermissionsRequired(scopes []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if a.settings == nil {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 
@@ -123,28 +131,28 @@ func (a *APIAuthorize) PermissionsRequired(scopes []string) gin.HandlerFunc {
 
 		if a.RequestFullPathHasAccountID(c) {
 			if a.settings.URLs == nil || len(a.settings.URLs) < 2 {
-				c.AbortWithStatus(http.StatusInternalServerError)
+				abortWithStatus(c, http.StatusInternalServerError)
 				return
 			}
 
 			accountID := c.Param(ACCOUNT_ID_PARAM)
 			response, err := a.GetAccounts(c, accountID, *a.settings.URLs[1])
 			if err != nil {
-				c.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(c, http.StatusForbidden)
 				return
 			}
 
 			defer response.Body.Close()
 
 			if response.StatusCode != http.StatusOK {
-				c.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(c, http.StatusForbidden)
 				return
 			}
 
 			identifier := new(string)
 			responseBody, _ := ioutil.ReadAll(response.Body)
 			if err := json.Unmarshal(responseBody, identifier); err != nil {
-				c.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(c, http.StatusForbidden)
 				return
 			}
 
@@ -154,7 +162,7 @@ func (a *APIAuthorize) PermissionsRequired(scopes []string) gin.HandlerFunc {
 		// current identity is required
 		currentIdentity := c.Request.Header.Get(X_CURRENT_IDENTITY)
 		if len(currentIdentity) == 0 {
-			c.AbortWithStatus(http.StatusInternalServerError)
+			abortWithStatus(c, http.StatusInternalServerError)
 			return
 		}
 
@@ -168,21 +176,44 @@ func (a *APIAuthorize) PermissionsRequired(scopes []string) gin.HandlerFunc {
 		jwt := c.Request.Header.Get("authorization")
 
 		for _, elemScope := range scopes {
-			if !a.verifyAuthorizationPermission(elemScope, jwt, currentIdentity, *url) {
+			if !a.verifyAuthorizationPermission(c.Request.Context(), elemScope, jwt, currentIdentity, *url) {
 				valid = false
-				c.AbortWithStatus(http.StatusForbidden)
+				abortWithStatus(c, http.StatusForbidden)
 				return
 			}
 		}
 
 		if !valid {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 		}
 
 		c.Next()
 	}
 }
 
+// VerifyPermissions ...
+func (a *APIAuthorize) VerifyPermissions(ctx context.Context, authorization string, currentIdentity string, scopes []string) error {
+	if a.settings == nil || len(currentIdentity) == 0 {
+		return ErrForbidden
+	}
+
+	url := a.settings.URL
+	if url == nil {
+		if len(a.settings.URLs) == 0 {
+			return NewError(http.StatusInternalServerError, "INTERNAL_AUTH_URL_NOT_FOUND", "internal auth url not configured")
+		}
+		url = a.settings.URLs[0]
+	}
+
+	for _, scope := range scopes {
+		if !a.verifyAuthorizationPermission(ctx, scope, authorization, currentIdentity, *url) {
+			return ErrForbidden
+		}
+	}
+
+	return nil
+}
+
 // IsPartner ...
 func IsPartner(c *gin.Context) bool {
 	permissions, exists := c.Get("permissions")
@@ -201,7 +232,7 @@ func IsPartner(c *gin.Context) bool {
 }
 
 // verifyAuthorizationPermission ...
-func (a *APIAuthorize) verifyAuthorizationPermission(scope string, jwt string,
+func (a *APIAuthorize) verifyAuthorizationPermission(ctx context.Context, scope string, jwt string,
 	currentIdentity string, url string) bool {
 
 	payload := struct {
@@ -215,7 +246,7 @@ func (a *APIAuthorize) verifyAuthorizationPermission(scope string, jwt string,
 		return false
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
 	if err != nil {
 		return false
 	}
@@ -224,7 +255,7 @@ func (a *APIAuthorize) verifyAuthorizationPermission(scope string, jwt string,
 	req.Header.Set("Authorization", jwt)
 	req.Header.Set(X_CURRENT_IDENTITY, currentIdentity)
 
-	client := http.Client{}
+	client := newHTTPClient("authorize")
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -253,7 +284,7 @@ func (a *APIAuthorize) PostAuthorization(c *gin.Context, scope string, URL strin
 		return nil, NewError(http.StatusForbidden, "SCOPE_NOT_FOUND", "error token scope not found")
 	}
 
-	req, err := http.NewRequest("POST", URL, bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", URL, bytes.NewReader(b))
 	if err != nil {
 		return nil, NewError(http.StatusForbidden, "ERROR_POST", "error on post to authorizations")
 	}
@@ -263,7 +294,7 @@ func (a *APIAuthorize) PostAuthorization(c *gin.Context, scope string, URL strin
 	req.Header.Set("Authorization", jwt)
 	req.Header.Set(X_CURRENT_IDENTITY, c.Request.Header.Get(X_CURRENT_IDENTITY))
 
-	client := http.Client{}
+	client := newHTTPClient("authorize")
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -275,7 +306,7 @@ func (a *APIAuthorize) PostAuthorization(c *gin.Context, scope string, URL strin
 func (a *APIAuthorize) GetAccounts(c *gin.Context, accountID string, URL string) (*http.Response, error) {
 
 	newURL := strings.Replace(URL, ":account_id", accountID, -1)
-	req, err := http.NewRequest("GET", newURL, nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", newURL, nil)
 	if err != nil {
 		return nil, NewError(http.StatusForbidden, "ERROR_GET", "error on get to accounts")
 	}
@@ -284,7 +315,7 @@ func (a *APIAuthorize) GetAccounts(c *gin.Context, accountID string, URL string)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", jwt)
 
-	client := http.Client{}
+	client := newHTTPClient("accounts")
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package grok
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,7 +24,7 @@ func NewFakeAuthorize(success bool) InternalAuthorize {
 func (a *FakeAuthorize) Authorize(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !a.alwaysSuccess {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 		c.Next()
@@ -34,18 +35,26 @@ func (a *FakeAuthorize) Authorize(scope string) gin.HandlerFunc {
 func (a *FakeAuthorize) PermissionRequired(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !a.alwaysSuccess {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 		c.Next()
 	}
 }
 
+// VerifyPermissions ...
+func (a *FakeAuthorize) VerifyPermissions(ctx context.Context, authorization string, currentIdentity string, scopes []string) error {
+	if !a.alwaysSuccess {
+		return ErrForbidden
+	}
+	return nil
+}
+
 // PermissionsRequired ...
 func (a *FakeAuthorize) PermissionsRequired(scopes []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !a.alwaysSuccess {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortWithStatus(c, http.StatusForbidden)
 			return
 		}
 		c.Next()
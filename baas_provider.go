@@ -1,6 +1,7 @@
 package grok
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -96,7 +97,7 @@ func (p *baasProvider) Identify() gin.HandlerFunc {
 		url := p.settings.URL
 		jwt := c.Request.Header.Get("authorization")
 
-		baasProvider := p.getBaasProviderForIdentifier(&currentIdentity, url, &jwt)
+		baasProvider := p.getBaasProviderForIdentifier(c.Request.Context(), &currentIdentity, url, &jwt)
 
 		c.Set(X_BAAS_PROVIDER, *baasProvider)
 
@@ -122,7 +123,7 @@ func (p *baasProviderIntra) Identify() gin.HandlerFunc {
 		url := p.settings.URL
 		jwt := c.Request.Header.Get("authorization")
 
-		baasProvider := p.getBaasProviderIntraForIdentifier(&currentIdentity, url, &jwt)
+		baasProvider := p.getBaasProviderIntraForIdentifier(c.Request.Context(), &currentIdentity, url, &jwt)
 
 		c.Set(X_BAAS_PROVIDER, *baasProvider)
 
@@ -131,7 +132,7 @@ func (p *baasProviderIntra) Identify() gin.HandlerFunc {
 }
 
 // getBaasProviderForIdentifier ...
-func (p *baasProvider) getBaasProviderForIdentifier(identifier *string, endpoint *string, jwt *string) *string {
+func (p *baasProvider) getBaasProviderForIdentifier(ctx context.Context, identifier *string, endpoint *string, jwt *string) *string {
 
 	baasProvider := DEFAULT_PROVIDER
 
@@ -143,7 +144,7 @@ func (p *baasProvider) getBaasProviderForIdentifier(identifier *string, endpoint
 	u.Path = path.Join(u.Path, *identifier)
 	newEndpoint := u.String()
 
-	req, err := http.NewRequest("GET", newEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", newEndpoint, nil)
 	if err != nil {
 		return &baasProvider
 	}
@@ -152,7 +153,7 @@ func (p *baasProvider) getBaasProviderForIdentifier(identifier *string, endpoint
 	req.Header.Set("Authorization", *jwt)
 	req.Header.Set(X_CURRENT_IDENTITY, *identifier)
 
-	client := http.Client{}
+	client := newHTTPClient("baas_provider")
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -176,7 +177,7 @@ func (p *baasProvider) getBaasProviderForIdentifier(identifier *string, endpoint
 }
 
 // getBaasProviderIntraForIdentifier ...
-func (p *baasProviderIntra) getBaasProviderIntraForIdentifier(identifier *string, endpoint *string, jwt *string) *string {
+func (p *baasProviderIntra) getBaasProviderIntraForIdentifier(ctx context.Context, identifier *string, endpoint *string, jwt *string) *string {
 
 	baasProvider := DEFAULT_PROVIDER
 
@@ -188,7 +189,7 @@ func (p *baasProviderIntra) getBaasProviderIntraForIdentifier(identifier *string
 	u.Path = path.Join(u.Path, *identifier)
 	newEndpoint := u.String()
 
-	req, err := http.NewRequest("GET", newEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", newEndpoint, nil)
 	if err != nil {
 		return &baasProvider
 	}
@@ -197,7 +198,7 @@ func (p *baasProviderIntra) getBaasProviderIntraForIdentifier(identifier *string
 	req.Header.Set("Authorization", *jwt)
 	req.Header.Set(X_CURRENT_IDENTITY, *identifier)
 
-	client := http.Client{}
+	client := newHTTPClient("baas_provider_intra")
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -15,7 +15,6 @@ import (
 	"fmt"
 	"hash"
 	"net/http"
-	"os"
 
 	"github.com/xdg-go/pbkdf2"
 )
@@ -176,33 +175,53 @@ func decryptPBES2(b, password []byte, maxIter int) (data, rest []byte, err error
 	return
 }
 
+// LoadCertificate loads a PEM certificate chain and its private key. Keys
+// encrypted as PKCS#8 (ENCRYPTED PRIVATE KEY) are decrypted with passphrase.
 func LoadCertificate(cert []byte, key []byte, passphrase string) (*tls.Certificate, error) {
 	block, _ := pem.Decode(key)
 	if block == nil {
 		return nil, NewError(http.StatusInternalServerError, "failed to decode PEM block")
 	}
 
-	var derKey []byte
-	var err error
-	password := []byte(passphrase)
-	if block.Type == "ENCRYPTED PRIVATE KEY" {
-		derKey, _, err = decryptPBES2(block.Bytes, password, 1000000)
+	if block.Type != "ENCRYPTED PRIVATE KEY" {
+		certificate, err := tls.X509KeyPair(cert, key)
 		if err != nil {
 			return nil, NewError(http.StatusInternalServerError, err.Error())
 		}
+		return &certificate, nil
 	}
 
-	privKey, err := x509.ParsePKCS8PrivateKey(derKey)
+	password := []byte(passphrase)
+	derKey, _, err := decryptPBES2(block.Bytes, password, 1000000)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "failed to parse PKCS #8 private key:", err)
-		os.Exit(1)
+		return nil, NewError(http.StatusInternalServerError, err.Error())
+	}
+	if derKey == nil {
+		return nil, NewError(http.StatusInternalServerError, "failed to decrypt private key")
 	}
 
-	certDERBlock, _ := pem.Decode([]byte(cert))
+	privKey, err := x509.ParsePKCS8PrivateKey(derKey)
+	if err != nil {
+		return nil, NewError(http.StatusInternalServerError, fmt.Sprintf("failed to parse PKCS #8 private key: %v", err))
+	}
 
 	var certificate tls.Certificate
 
-	certificate.Certificate = append(certificate.Certificate, certDERBlock.Bytes)
+	for rest := cert; ; {
+		var certDERBlock *pem.Block
+		certDERBlock, rest = pem.Decode(rest)
+		if certDERBlock == nil {
+			break
+		}
+		if certDERBlock.Type == "CERTIFICATE" {
+			certificate.Certificate = append(certificate.Certificate, certDERBlock.Bytes)
+		}
+	}
+
+	if len(certificate.Certificate) == 0 {
+		return nil, NewError(http.StatusInternalServerError, "failed to decode certificate PEM block")
+	}
+
 	certificate.PrivateKey = privKey.(crypto.PrivateKey)
 
 	return &certificate, nil
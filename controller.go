@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 //APIController ...
@@ -15,20 +16,36 @@ type APIController interface {
 //BindingError ...
 func BindingError(context *gin.Context, err error) {
 	context.Error(err)
-	context.JSON(http.StatusBadRequest, NewError(http.StatusBadRequest, err.Error()))
+
+	if !IsProblemJSON(context) {
+		context.JSON(http.StatusBadRequest, NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	problem := NewError(http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	if _, ok := err.(validator.ValidationErrors); ok {
+		problem = FromLocalizedValidationErrors(err, context.GetHeader("Accept-Language"))
+	}
+
+	RenderError(context, http.StatusBadRequest, problem)
 }
 
 //ResolveError ...
 func ResolveError(context *gin.Context, err error) {
 	context.Error(err)
 
-	errMapping := DefaultErrorMapping.Get(err.Error())
+	errMapping := ResolveMappedError(err)
 
 	if errMapping != nil {
 		err = errMapping
 	}
 
 	if reflect.TypeOf(err) != reflect.TypeOf(&Error{}) {
+		if IsProblemJSON(context) {
+			RenderError(context, http.StatusInternalServerError,
+				NewError(http.StatusInternalServerError, "INTERNAL_SERVER_ERROR"))
+			return
+		}
 		context.Status(http.StatusInternalServerError)
 		return
 	}
@@ -40,5 +57,5 @@ func ResolveError(context *gin.Context, err error) {
 		status = message.Code
 	}
 
-	context.JSON(status, message)
+	RenderError(context, status, message)
 }
@@ -2,11 +2,22 @@ package grok
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-// CORS ...
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", "Accept", "X-Token", "X-Current-Identity"}
+)
+
+// CORS reflects any origin and allows credentials.
+//
+// Deprecated: configure APISettings.CORS, which WithCORS applies through
+// CORSWithSettings.
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.GetHeader("Origin")
@@ -23,3 +34,136 @@ func CORS() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// CORSWithSettings only answers origins in the allowlist. Origins may use a
+// wildcard subdomain, e.g. https://*.contbank.com, or be * to allow any
+// origin without credentials. Preflights from other origins get 403.
+func CORSWithSettings(settings *CORSSettings) gin.HandlerFunc {
+	methods := defaultCORSMethods
+	if len(settings.AllowedMethods) > 0 {
+		methods = settings.AllowedMethods
+	}
+
+	headers := defaultCORSHeaders
+	if len(settings.AllowedHeaders) > 0 {
+		headers = settings.AllowedHeaders
+	}
+
+	anyOrigin := false
+	for _, origin := range settings.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+	}
+
+	credentials := settings.AllowCredentials
+	if anyOrigin && credentials {
+		logrus.Warn("cors credentials are not allowed with any origin, disabling credentials")
+		credentials = false
+	}
+
+	anyHeader := containsFold(headers, "*")
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !anyOrigin && !allowedOrigin(settings.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if anyOrigin {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(settings.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(settings.ExposedHeaders, ", "))
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+
+		if !containsFold(methods, c.GetHeader("Access-Control-Request-Method")) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+		if anyHeader {
+			if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else {
+			header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+
+		if settings.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.FormatInt(settings.MaxAge, 10))
+		}
+
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func allowedOrigin(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+
+		if pattern == origin {
+			return true
+		}
+
+		wildcard := strings.Index(pattern, "*")
+		if wildcard < 0 {
+			continue
+		}
+
+		prefix, suffix := pattern[:wildcard], pattern[wildcard+1:]
+		if len(origin) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+
+		subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(subdomain, "/:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package grok_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsEngine(settings *grok.CORSSettings) *gin.Engine {
+	server := grok.New(
+		grok.WithSettings(&grok.Settings{API: &grok.APISettings{CORS: settings}}),
+		grok.WithCORS(),
+		grok.WithContainer(&testContainer{}))
+	server.Engine.GET("/customers", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return server.Engine
+}
+
+func corsRequest(engine *gin.Engine, method string, origin string, requestMethod string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/customers", nil)
+	req.Header.Set("Origin", origin)
+	if requestMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", requestMethod)
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	}
+	engine.ServeHTTP(response, req)
+	return response
+}
+
+func TestCORSWithSettings(t *testing.T) {
+	engine := corsEngine(&grok.CORSSettings{
+		AllowedOrigins:   []string{"https://app.contbank.com", "https://*.contbank.dev"},
+		AllowedMethods:   []string{"GET", "POST"},
+		ExposedHeaders:   []string{"Request-Id"},
+		MaxAge:           600,
+		AllowCredentials: true,
+	})
+
+	response := corsRequest(engine, "GET", "https://app.contbank.com", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "https://app.contbank.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Request-Id", response.Header().Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, response.Header().Values("Vary"), "Origin")
+
+	response = corsRequest(engine, "OPTIONS", "https://admin.contbank.dev", "POST")
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "https://admin.contbank.dev", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", response.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "600", response.Header().Get("Access-Control-Max-Age"))
+
+	response = corsRequest(engine, "OPTIONS", "https://app.contbank.com", "DELETE")
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	response = corsRequest(engine, "OPTIONS", "https://evil.com", "GET")
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	response = corsRequest(engine, "GET", "https://contbank.dev.evil.com", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	engine := corsEngine(&grok.CORSSettings{
+		AllowedOrigins:   []string{"*"},
+		AllowedHeaders:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	response := corsRequest(engine, "OPTIONS", "https://any.com", "GET")
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "*", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Authorization", response.Header().Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSWithoutSettings(t *testing.T) {
+	engine := corsEngine(nil)
+
+	response := corsRequest(engine, "GET", "https://evil.com", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Credentials"))
+
+	response = corsRequest(engine, "OPTIONS", "https://evil.com", "GET")
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
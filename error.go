@@ -2,10 +2,7 @@ package grok
 
 import (
 	"fmt"
-	"net/http"
 	"strings"
-
-	"github.com/go-playground/validator/v10"
 )
 
 // Error ...
@@ -13,6 +10,26 @@ type Error struct {
 	Code     int      `json:"code"`
 	Key      string   `json:"key"`
 	Messages []string `json:"messages"`
+
+	// problem document members, not part of the legacy format
+	Type     string       `json:"-"`
+	Title    string       `json:"-"`
+	Detail   string       `json:"-"`
+	Instance string       `json:"-"`
+	Errors   []FieldError `json:"-"`
+
+	// cause is the error this one was mapped from by ErrorRules or
+	// ErrorMapping
+	cause error
+}
+
+// FieldError describes a field failing validation
+type FieldError struct {
+	Field   string      `json:"field"`
+	Message string      `json:"message"`
+	Tag     string      `json:"tag,omitempty"`
+	Param   string      `json:"param,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
 }
 
 // NewError  ...
@@ -20,23 +37,15 @@ func NewError(code int, key string, messages ...string) *Error {
 	return &Error{Code: code, Key: key, Messages: messages}
 }
 
-// FromValidationErros ...
+// FromValidationErros converts validation errors into an Error with the
+// messages in DefaultLanguage
 func FromValidationErros(errors error) *Error {
-	validationErrors, ok := errors.(validator.ValidationErrors)
-
-	if !ok {
-		return NewError(0, "cannot parse validation errors")
-	}
-
-	err := NewError(http.StatusUnprocessableEntity, "INVALID_PARAMETER")
-
-	message := "validation failed for %s"
-
-	for _, e := range validationErrors {
-		err.Messages = append(err.Messages, fmt.Sprintf(message, e.Field()))
-	}
+	return FromLocalizedValidationErrors(errors, DefaultLanguage)
+}
 
-	return err
+// Unwrap returns the error this one was mapped from, if any
+func (e *Error) Unwrap() error {
+	return e.cause
 }
 
 func (e *Error) Error() string {
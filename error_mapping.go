@@ -1,13 +1,39 @@
 package grok
 
-import "strings"
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 
-// ErrorMapping ...
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrorMapping maps errors whose message contains a key, case insensitive.
+// When several keys match the longest one wins. Use ErrorRules to match
+// errors by type or in a given order.
 type ErrorMapping map[string]error
 
+// ErrorRules translates errors into API errors. Rules are evaluated in
+// registration order and the first match wins.
+type ErrorRules struct {
+	mutex sync.RWMutex
+	rules []errorRule
+}
+
+type errorRule struct {
+	text   func(string) bool
+	match  func(error) bool
+	result error
+}
+
 var (
 	// DefaultErrorMapping ...
 	DefaultErrorMapping = ErrorMapping{}
+	// DefaultErrorRules are evaluated before DefaultErrorMapping
+	DefaultErrorRules = NewErrorRules()
 )
 
 // Register ...
@@ -15,12 +41,163 @@ func (mapping ErrorMapping) Register(k string, v error) {
 	mapping[k] = v
 }
 
-// Get ...
+// Get returns the result of the longest key contained in err
 func (mapping ErrorMapping) Get(err string) error {
-	for key, result := range mapping {
-		if strings.Contains(strings.ToLower(err), strings.ToLower(key)) {
-			return result
+	err = strings.ToLower(err)
+
+	match, found := "", false
+	var result error
+	for key, value := range mapping {
+		if !strings.Contains(err, strings.ToLower(key)) {
+			continue
+		}
+		if !found || len(key) > len(match) || (len(key) == len(match) && key < match) {
+			match, found, result = key, true, value
+		}
+	}
+	return result
+}
+
+// Resolve returns the result of the longest key contained in the message
+// of err, or nil
+func (mapping ErrorMapping) Resolve(err error) error {
+	if err == nil {
+		return nil
+	}
+	return mappedError(mapping.Get(err.Error()), err)
+}
+
+// NewErrorRules ...
+func NewErrorRules() *ErrorRules {
+	return &ErrorRules{}
+}
+
+// Register maps errors whose message contains k, case insensitive
+func (rules *ErrorRules) Register(k string, v error) {
+	key := strings.ToLower(k)
+	rules.add(errorRule{
+		text: func(err string) bool {
+			return strings.Contains(strings.ToLower(err), key)
+		},
+		result: v,
+	})
+}
+
+// RegisterRegexp maps errors whose message matches re
+func (rules *ErrorRules) RegisterRegexp(re *regexp.Regexp, v error) {
+	rules.add(errorRule{text: re.MatchString, result: v})
+}
+
+// RegisterIs maps errors matching target with errors.Is, e.g.
+// context.DeadlineExceeded or mongo.ErrNoDocuments
+func (rules *ErrorRules) RegisterIs(target error, v error) {
+	rules.RegisterFunc(func(err error) bool {
+		return errors.Is(err, target)
+	}, v)
+}
+
+// RegisterAs maps errors matching the type target points to with errors.As,
+// e.g. new(mongo.WriteException) or new(awserr.Error)
+func (rules *ErrorRules) RegisterAs(target interface{}, v error) {
+	targetType := reflect.TypeOf(target)
+	if targetType == nil || targetType.Kind() != reflect.Ptr {
+		panic("grok: RegisterAs target must be a non-nil pointer")
+	}
+
+	rules.RegisterFunc(func(err error) bool {
+		return errors.As(err, reflect.New(targetType.Elem()).Interface())
+	}, v)
+}
+
+// RegisterFunc maps errors for which match returns true
+func (rules *ErrorRules) RegisterFunc(match func(error) bool, v error) {
+	rules.add(errorRule{match: match, result: v})
+}
+
+func (rules *ErrorRules) add(rule errorRule) {
+	rules.mutex.Lock()
+	defer rules.mutex.Unlock()
+
+	rules.rules = append(rules.rules, rule)
+}
+
+// Get returns the result of the first message rule matching err
+func (rules *ErrorRules) Get(err string) error {
+	rules.mutex.RLock()
+	defer rules.mutex.RUnlock()
+
+	for _, rule := range rules.rules {
+		if rule.text != nil && rule.text(err) {
+			return rule.result
 		}
 	}
 	return nil
 }
+
+// Resolve returns the result of the first rule matching err, or nil. A
+// mapped *Error is a copy that unwraps to err so the cause can be logged.
+func (rules *ErrorRules) Resolve(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	rules.mutex.RLock()
+	defer rules.mutex.RUnlock()
+
+	for _, rule := range rules.rules {
+		matched := false
+		if rule.text != nil {
+			matched = rule.text(err.Error())
+		} else {
+			matched = rule.match(err)
+		}
+
+		if matched {
+			return mappedError(rule.result, err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveMappedError resolves err with DefaultErrorRules and then with
+// DefaultErrorMapping, returning nil when neither matches
+func ResolveMappedError(err error) error {
+	if mapped := DefaultErrorRules.Resolve(err); mapped != nil {
+		return mapped
+	}
+	return DefaultErrorMapping.Resolve(err)
+}
+
+// mappedError copies a *Error result so it unwraps to the original error
+func mappedError(result error, err error) error {
+	if e, ok := result.(*Error); ok {
+		mapped := *e
+		mapped.cause = err
+		return &mapped
+	}
+	return result
+}
+
+// MatchDuplicateKey matches mongo duplicate key errors
+func MatchDuplicateKey(err error) bool {
+	return mongo.IsDuplicateKeyError(err)
+}
+
+// MatchAWSErrorCode matches aws errors with one of the given codes, e.g.
+// sns.ErrCodeNotFoundException
+func MatchAWSErrorCode(codes ...string) func(error) bool {
+	return func(err error) bool {
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) {
+			return false
+		}
+
+		for _, code := range codes {
+			if aerr.Code() == code {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package grok_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/contbank/grok"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type customerError struct {
+	id string
+}
+
+func (e *customerError) Error() string {
+	return "customer " + e.id + " is blocked"
+}
+
+func TestErrorMappingLongestKey(t *testing.T) {
+	notFound := grok.NewError(http.StatusNotFound, "NOT_FOUND")
+	accountNotFound := grok.NewError(http.StatusNotFound, "ACCOUNT_NOT_FOUND")
+
+	mapping := grok.ErrorMapping{"not found": notFound}
+	mapping.Register("Account Not Found", accountNotFound)
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, accountNotFound, mapping.Get("account not found"))
+		assert.Equal(t, notFound, mapping.Get("customer not found"))
+	}
+
+	assert.Nil(t, mapping.Get("unexpected"))
+
+	resolved := mapping.Resolve(errors.New("account not found"))
+	assert.Equal(t, "ACCOUNT_NOT_FOUND", resolved.(*grok.Error).Key)
+	assert.Nil(t, errors.Unwrap(accountNotFound))
+}
+
+func TestResolveMappedError(t *testing.T) {
+	grok.DefaultErrorMapping.Register("customer not found", grok.NewError(http.StatusNotFound, "CUSTOMER_NOT_FOUND"))
+	defer delete(grok.DefaultErrorMapping, "customer not found")
+
+	errSuspended := errors.New("customer suspended")
+	grok.DefaultErrorRules.RegisterIs(errSuspended, grok.NewError(http.StatusForbidden, "SUSPENDED"))
+
+	assert.Equal(t, "CUSTOMER_NOT_FOUND", grok.ResolveMappedError(errors.New("customer not found")).(*grok.Error).Key)
+	assert.Equal(t, "SUSPENDED", grok.ResolveMappedError(fmt.Errorf("query: %w", errSuspended)).(*grok.Error).Key)
+	assert.Nil(t, grok.ResolveMappedError(errors.New("unexpected")))
+}
+
+func TestErrorRulesOrder(t *testing.T) {
+	notFound := grok.NewError(http.StatusNotFound, "NOT_FOUND")
+	accountNotFound := grok.NewError(http.StatusNotFound, "ACCOUNT_NOT_FOUND")
+
+	mapping := grok.NewErrorRules()
+	mapping.Register("account not found", accountNotFound)
+	mapping.Register("not found", notFound)
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, accountNotFound, mapping.Get("Account Not Found"))
+		assert.Equal(t, notFound, mapping.Get("customer not found"))
+	}
+
+	assert.Nil(t, mapping.Get("unexpected"))
+}
+
+func TestErrorRulesResolve(t *testing.T) {
+	timeout := grok.NewError(http.StatusGatewayTimeout, "TIMEOUT")
+	duplicated := grok.NewError(http.StatusConflict, "DUPLICATED")
+	blocked := grok.NewError(http.StatusForbidden, "BLOCKED")
+	topic := grok.NewError(http.StatusBadGateway, "TOPIC_NOT_FOUND")
+	invalid := grok.NewError(http.StatusBadRequest, "INVALID_AMOUNT")
+
+	mapping := grok.NewErrorRules()
+	mapping.RegisterIs(context.DeadlineExceeded, timeout)
+	mapping.RegisterFunc(grok.MatchDuplicateKey, duplicated)
+	mapping.RegisterAs(new(*customerError), blocked)
+	mapping.RegisterFunc(grok.MatchAWSErrorCode(sns.ErrCodeNotFoundException), topic)
+	mapping.RegisterRegexp(regexp.MustCompile(`^amount \d+ exceeds`), invalid)
+
+	var items = []struct {
+		err      error
+		expected *grok.Error
+	}{
+		{fmt.Errorf("publishing: %w", context.DeadlineExceeded), timeout},
+		{mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, duplicated},
+		{fmt.Errorf("transfer: %w", &customerError{id: "1"}), blocked},
+		{awserr.New(sns.ErrCodeNotFoundException, "topic not found", nil), topic},
+		{errors.New("amount 100 exceeds the limit"), invalid},
+	}
+
+	for _, item := range items {
+		resolved := mapping.Resolve(item.err)
+
+		e, ok := resolved.(*grok.Error)
+		assert.True(t, ok, item.err.Error())
+		assert.Equal(t, item.expected.Key, e.Key)
+		assert.Equal(t, item.expected.Code, e.Code)
+		assert.Equal(t, item.err, errors.Unwrap(e))
+	}
+
+	assert.Nil(t, mapping.Resolve(errors.New("unexpected")))
+	assert.Nil(t, mapping.Resolve(nil))
+	assert.Nil(t, errors.Unwrap(timeout))
+}
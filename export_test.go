@@ -0,0 +1,29 @@
+package grok
+
+import "time"
+
+// SetNow replaces the clock of the store in tests
+func (s *MemoryRateLimitStore) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+// Keys returns the number of keys tracked by the store
+func (s *MemoryRateLimitStore) Keys() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.idle) + len(s.buckets) + len(s.windows)
+}
+
+// SetTouchInterval replaces the touch interval in tests
+func (a *APIKeyAuthenticate) SetTouchInterval(interval time.Duration) {
+	a.touchInterval = interval
+}
+
+// Touched returns the number of keys tracked as recently touched
+func (a *APIKeyAuthenticate) Touched() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return len(a.touched)
+}
@@ -0,0 +1,255 @@
+// Package groktest provides helpers to test grok services offline
+package groktest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/contbank/grok"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// LocalIssuerJWKSPath ...
+	LocalIssuerJWKSPath = "/.well-known/jwks.json"
+
+	defaultLocalAudience = "https://api.contbank.com"
+	defaultLocalSubject  = "local|user"
+	defaultLocalExpiry   = time.Hour
+)
+
+// LocalIssuer signs tokens with generated keys and serves its JWKS and
+// OpenID Connect discovery document with httptest, so the Auth0 and OIDC
+// authenticators can be exercised offline in tests and development.
+type LocalIssuer struct {
+	Server    *httptest.Server
+	Audience  string
+	Algorithm jose.SignatureAlgorithm
+
+	mutex sync.RWMutex
+	keys  []jose.JSONWebKey
+}
+
+// LocalIssuerOption ...
+type LocalIssuerOption func(*LocalIssuer)
+
+// WithIssuerAudience sets the default token audience,
+// default https://api.contbank.com
+func WithIssuerAudience(audience string) LocalIssuerOption {
+	return func(i *LocalIssuer) {
+		i.Audience = audience
+	}
+}
+
+// WithIssuerAlgorithm sets the signature algorithm, RS256 (default) or ES256
+func WithIssuerAlgorithm(algorithm jose.SignatureAlgorithm) LocalIssuerOption {
+	return func(i *LocalIssuer) {
+		i.Algorithm = algorithm
+	}
+}
+
+// NewLocalIssuer starts the issuer server. It must be closed with Close.
+func NewLocalIssuer(opts ...LocalIssuerOption) (*LocalIssuer, error) {
+	issuer := &LocalIssuer{
+		Audience:  defaultLocalAudience,
+		Algorithm: jose.RS256,
+	}
+
+	for _, opt := range opts {
+		opt(issuer)
+	}
+
+	if err := issuer.RotateKey(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(grok.OIDCDiscoveryPath, issuer.discovery)
+	mux.HandleFunc(LocalIssuerJWKSPath, issuer.jwks)
+
+	issuer.Server = httptest.NewServer(mux)
+
+	return issuer, nil
+}
+
+// Close ...
+func (i *LocalIssuer) Close() {
+	i.Server.Close()
+}
+
+// Issuer returns the iss claim of the tokens, ending with a slash as Auth0
+// tenants do
+func (i *LocalIssuer) Issuer() string {
+	return i.Server.URL + "/"
+}
+
+// JWKSURL ...
+func (i *LocalIssuer) JWKSURL() string {
+	return i.Server.URL + LocalIssuerJWKSPath
+}
+
+// APIAuth returns the settings validating the issuer tokens with
+// grok.NewAuthenticate
+func (i *LocalIssuer) APIAuth() *grok.APIAuth {
+	return &grok.APIAuth{
+		Tenant:   i.Issuer(),
+		JWKS:     i.JWKSURL(),
+		Audience: []string{i.Audience},
+	}
+}
+
+// OIDCAPIAuth returns the settings validating the issuer tokens with
+// grok.NewOIDCAuthenticate
+func (i *LocalIssuer) OIDCAPIAuth() *grok.APIAuth {
+	return &grok.APIAuth{
+		Provider:   grok.OIDCProvider,
+		Issuers:    []string{i.Issuer()},
+		Audience:   []string{i.Audience},
+		Algorithms: []string{string(i.Algorithm)},
+	}
+}
+
+// RotateKey generates a new signing key. Previous keys are still served so
+// tokens they signed remain valid.
+func (i *LocalIssuer) RotateKey() error {
+	var key crypto.Signer
+	var err error
+
+	switch i.Algorithm {
+	case jose.RS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jose.ES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return fmt.Errorf("unsupported algorithm %s", i.Algorithm)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.keys = append(i.keys, jose.JSONWebKey{
+		Key:       key,
+		KeyID:     fmt.Sprintf("local-%d", len(i.keys)+1),
+		Algorithm: string(i.Algorithm),
+		Use:       "sig",
+	})
+
+	return nil
+}
+
+// TokenOption ...
+type TokenOption func(claims map[string]interface{})
+
+// WithClaim sets an arbitrary claim
+func WithClaim(key string, value interface{}) TokenOption {
+	return func(claims map[string]interface{}) {
+		claims[key] = value
+	}
+}
+
+// WithoutClaim removes a claim, e.g. exp
+func WithoutClaim(key string) TokenOption {
+	return func(claims map[string]interface{}) {
+		delete(claims, key)
+	}
+}
+
+// WithSubject sets the sub claim, default local|user
+func WithSubject(subject string) TokenOption {
+	return WithClaim("sub", subject)
+}
+
+// WithAudience sets the aud claim
+func WithAudience(audience ...string) TokenOption {
+	return WithClaim("aud", audience)
+}
+
+// WithPermissions sets the permissions claim
+func WithPermissions(permissions ...string) TokenOption {
+	return WithClaim("permissions", permissions)
+}
+
+// WithStores sets the stores custom claim under AuthClaimNamespace
+func WithStores(stores ...string) TokenOption {
+	return WithClaim(grok.AuthClaimNamespace+"stores", stores)
+}
+
+// WithExpiry sets the exp claim to now plus expiry, negative for expired
+// tokens, default 1h
+func WithExpiry(expiry time.Duration) TokenOption {
+	return WithClaim("exp", time.Now().Add(expiry).Unix())
+}
+
+// Token mints a token signed with the current key
+func (i *LocalIssuer) Token(opts ...TokenOption) (string, error) {
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss": i.Issuer(),
+		"sub": defaultLocalSubject,
+		"aud": []string{i.Audience},
+		"iat": now.Unix(),
+		"exp": now.Add(defaultLocalExpiry).Unix(),
+	}
+
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	i.mutex.RLock()
+	key := i.keys[len(i.keys)-1]
+	i.mutex.RUnlock()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: key},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Signed(signer).Claims(claims).CompactSerialize()
+}
+
+// Authorization mints a token and returns it as a bearer authorization
+// header value
+func (i *LocalIssuer) Authorization(opts ...TokenOption) (string, error) {
+	token, err := i.Token(opts...)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+func (i *LocalIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":   i.Issuer(),
+		"jwks_uri": i.JWKSURL(),
+	})
+}
+
+func (i *LocalIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	i.mutex.RLock()
+	keys := jose.JSONWebKeySet{}
+	for _, key := range i.keys {
+		keys.Keys = append(keys.Keys, key.Public())
+	}
+	i.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
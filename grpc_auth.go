@@ -0,0 +1,145 @@
+package grok
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// GRPCAuthorizationMetadata ...
+	GRPCAuthorizationMetadata = "authorization"
+	// GRPCCurrentIdentityMetadata ...
+	GRPCCurrentIdentityMetadata = "x-current-identity"
+)
+
+// grpcPublicMethods never require authentication
+var grpcPublicMethods = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// GRPCMethodScopes maps a full grpc method name, e.g. /package.Service/Method,
+// to the permissions it requires. Methods not declared only require a valid
+// token.
+type GRPCMethodScopes map[string][]string
+
+// AuthUnaryServerInterceptor validates the authorization metadata with
+// authenticate, puts the claims into the context and enforces the scopes
+// declared for the method. Scopes are verified through authorize when it is
+// not nil, otherwise against the token permissions claim. It panics when
+// authenticate is not a TokenValidator or authorize not a
+// PermissionVerifier.
+func AuthUnaryServerInterceptor(authenticate Authenticate, authorize InternalAuthorize, scopes GRPCMethodScopes) grpc.UnaryServerInterceptor {
+	validator, verifier := grpcAuthenticators(authenticate, authorize)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := grpcAuth(ctx, info.FullMethod, validator, verifier, scopes)
+		if err != nil {
+			return nil, GRPCError(err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamServerInterceptor ...
+func AuthStreamServerInterceptor(authenticate Authenticate, authorize InternalAuthorize, scopes GRPCMethodScopes) grpc.StreamServerInterceptor {
+	validator, verifier := grpcAuthenticators(authenticate, authorize)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := grpcAuth(ss.Context(), info.FullMethod, validator, verifier, scopes)
+		if err != nil {
+			return GRPCError(err)
+		}
+
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func grpcAuthenticators(authenticate Authenticate, authorize InternalAuthorize) (TokenValidator, PermissionVerifier) {
+	validator, ok := authenticate.(TokenValidator)
+	if !ok {
+		logrus.Panicf("grpc authentication requires a TokenValidator, %T is not", authenticate)
+	}
+
+	if authorize == nil {
+		return validator, nil
+	}
+
+	verifier, ok := authorize.(PermissionVerifier)
+	if !ok {
+		logrus.Panicf("grpc authorization requires a PermissionVerifier, %T is not", authorize)
+	}
+
+	return validator, verifier
+}
+
+func grpcAuth(ctx context.Context, method string, authenticate TokenValidator, authorize PermissionVerifier, scopes GRPCMethodScopes) (context.Context, error) {
+	for _, public := range grpcPublicMethods {
+		if strings.HasPrefix(method, public) {
+			return ctx, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := metadataValue(md, GRPCAuthorizationMetadata)
+
+	claims, err := authenticate.ValidateToken(ctx, authorization)
+	if err != nil {
+		return ctx, ErrUnauthorized
+	}
+
+	ctx = ContextWithClaims(ctx, claims)
+
+	required := scopes[method]
+	if len(required) == 0 {
+		return ctx, nil
+	}
+
+	if authorize != nil {
+		currentIdentity := metadataValue(md, GRPCCurrentIdentityMetadata)
+		if err := authorize.VerifyPermissions(ctx, authorization, currentIdentity, required); err != nil {
+			return ctx, err
+		}
+		return ctx, nil
+	}
+
+	if !hasPermissions(ctx.Value("permissions"), required) {
+		return ctx, ErrForbidden
+	}
+
+	return ctx, nil
+}
+
+func hasPermissions(value interface{}, scopes []string) bool {
+	permissions, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, scope := range scopes {
+		found := false
+		for _, permission := range permissions {
+			if permission == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func metadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
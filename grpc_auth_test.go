@@ -0,0 +1,111 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func grpcAuthContext(identity string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		grok.GRPCAuthorizationMetadata, "Bearer token",
+		grok.GRPCCurrentIdentityMetadata, identity,
+	))
+}
+
+func grpcOK(ctx context.Context, req interface{}) (interface{}, error) {
+	return ctx.Value("sub"), nil
+}
+
+func TestGRPCAuthInterceptorFake(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":                              "auth0|123",
+		grok.AuthClaimNamespace + "stores": []interface{}{"store"},
+		"permissions":                      []interface{}{"read:customers"},
+	}
+	scopes := grok.GRPCMethodScopes{
+		"/grok.Test/Read":  {"read:customers"},
+		"/grok.Test/Write": {"write:customers"},
+	}
+
+	interceptor := grok.AuthUnaryServerInterceptor(grok.NewFakeAuthenticate(true, claims), nil, scopes)
+
+	resp, err := interceptor(grpcAuthContext(""), nil, &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Read"}, grpcOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "auth0|123", resp)
+
+	_, err = interceptor(grpcAuthContext(""), nil, &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Read"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			assert.Equal(t, []interface{}{"store"}, ctx.Value("stores"))
+			return nil, nil
+		})
+	assert.NoError(t, err)
+
+	_, err = interceptor(grpcAuthContext(""), nil, &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Write"}, grpcOK)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	unauthenticated := grok.AuthUnaryServerInterceptor(grok.NewFakeAuthenticate(false, nil), nil, scopes)
+
+	_, err = unauthenticated(grpcAuthContext(""), nil, &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Read"}, grpcOK)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = unauthenticated(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, grpcOK)
+	assert.NoError(t, err)
+}
+
+func TestGRPCAuthInterceptorInternalAuthorize(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if r.Header.Get(grok.CurrentIdentityHeader) != "12345678909" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	scopes := grok.GRPCMethodScopes{"/grok.Test/Read": {"read:customers"}}
+	authenticate := grok.NewFakeAuthenticate(true, map[string]interface{}{"sub": "auth0|123"})
+	authorize := grok.CreateAuthorize(&grok.InternalAuth{URLs: []*string{grok.String(upstream.URL)}})
+
+	interceptor := grok.AuthUnaryServerInterceptor(authenticate, authorize, scopes)
+	info := &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Read"}
+
+	_, err := interceptor(grpcAuthContext("12345678909"), nil, info, grpcOK)
+	assert.NoError(t, err)
+
+	_, err = interceptor(grpcAuthContext("00000000000"), nil, info, grpcOK)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	fake := grok.AuthUnaryServerInterceptor(authenticate,
+		grok.CreateAuthorize(&grok.InternalAuth{Fake: true, Success: new(bool)}), scopes)
+
+	_, err = fake(grpcAuthContext("12345678909"), nil, info, grpcOK)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+type middlewareOnlyAuthenticate struct{}
+
+func (a *middlewareOnlyAuthenticate) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) { c.Next() }
+}
+
+func TestGRPCAuthInterceptorRequiresTokenValidator(t *testing.T) {
+	assert.Panics(t, func() {
+		grok.AuthUnaryServerInterceptor(&middlewareOnlyAuthenticate{}, nil, nil)
+	})
+
+	assert.NotPanics(t, func() {
+		grok.AuthUnaryServerInterceptor(grok.NewFakeAuthenticate(true, nil), grok.NewFakeAuthorize(true), nil)
+		grok.AuthStreamServerInterceptor(grok.NewAuthenticate(&grok.APIAuth{}, nil), grok.NewInternalAuthorize(&grok.InternalAuth{}), nil)
+	})
+}
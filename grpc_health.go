@@ -0,0 +1,72 @@
+package grok
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	grpcHealthService          = "grpc.health.v1.Health"
+	defaultHealthCheckInterval = 10 * time.Second
+)
+
+// registerGRPCHealth registers the standard health service unless the
+// application already did it
+func (server *API) registerGRPCHealth() {
+	if _, ok := server.grpcServer.GetServiceInfo()[grpcHealthService]; ok {
+		logrus.Info("grpc health service already registered")
+		return
+	}
+
+	server.healthServer = health.NewServer()
+	healthpb.RegisterHealthServer(server.grpcServer, server.healthServer)
+}
+
+func (server *API) healthCheckInterval() time.Duration {
+	if server.settings.GRPC != nil && server.settings.GRPC.HealthCheckInterval > 0 {
+		return time.Duration(server.settings.GRPC.HealthCheckInterval) * time.Second
+	}
+	return defaultHealthCheckInterval
+}
+
+// watchGRPCHealth periodically updates the health status until stop is closed
+func (server *API) watchGRPCHealth(stop <-chan struct{}) {
+	if server.healthServer == nil {
+		return
+	}
+
+	ticker := time.NewTicker(server.healthCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		server.updateGRPCHealth()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (server *API) updateGRPCHealth() {
+	status := healthpb.HealthCheckResponse_SERVING
+
+	if !server.Ready() {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	} else if server.grpcHealthz != nil {
+		if err := server.grpcHealthz.Healthz(); err != nil {
+			logrus.WithError(err).Error("grpc health check failed")
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+
+	server.healthServer.SetServingStatus("", status)
+
+	for service := range server.grpcServer.GetServiceInfo() {
+		server.healthServer.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,238 @@
+package grok
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// GRPCRequestIDMetadata ...
+	GRPCRequestIDMetadata = "x-request-id"
+)
+
+// GRPCServerOptions returns grok interceptors for request ids, tracing,
+// logging, recovery and error mapping
+func GRPCServerOptions(restricteds []string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			RequestIDUnaryServerInterceptor(),
+			TracingUnaryServerInterceptor(),
+			LoggingUnaryServerInterceptor(restricteds),
+			RecoveryUnaryServerInterceptor(),
+			ErrorUnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			RequestIDStreamServerInterceptor(),
+			TracingStreamServerInterceptor(),
+			LoggingStreamServerInterceptor(),
+			RecoveryStreamServerInterceptor(),
+			ErrorStreamServerInterceptor(),
+		),
+	}
+}
+
+// RequestIDUnaryServerInterceptor reuses the x-request-id metadata, when it
+// is a valid request id as in the HTTP middleware, or generates a new id,
+// making it available through GetRequestID
+func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(grpcRequestID(ctx), req)
+	}
+}
+
+// RequestIDStreamServerInterceptor ...
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: grpcRequestID(ss.Context())})
+	}
+}
+
+func grpcRequestID(ctx context.Context) context.Context {
+	requestID := ""
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(GRPCRequestIDMetadata); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+
+	requestID = inboundRequestID(requestID)
+
+	grpc.SetHeader(ctx, metadata.Pairs(GRPCRequestIDMetadata, requestID))
+
+	return ContextWithRequestID(ctx, requestID)
+}
+
+// LoggingUnaryServerInterceptor logs requests with the same restricted field
+// redaction used by LogMiddleware
+func LoggingUnaryServerInterceptor(restricteds []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		now := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		fields := grpcLogFields(ctx, info.FullMethod, now, err)
+		fields["request"] = restricted(req, restricteds)
+		fields["response"] = restricted(resp, restricteds)
+
+		logrus.WithFields(fields).Infof(
+			"GRPC call %s elapsed %s completed with %s",
+			info.FullMethod,
+			time.Since(now).String(),
+			status.Code(err),
+		)
+
+		return resp, err
+	}
+}
+
+// LoggingStreamServerInterceptor ...
+func LoggingStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		now := time.Now()
+
+		err := handler(srv, ss)
+
+		logrus.WithFields(grpcLogFields(ss.Context(), info.FullMethod, now, err)).Infof(
+			"GRPC stream %s elapsed %s completed with %s",
+			info.FullMethod,
+			time.Since(now).String(),
+			status.Code(err),
+		)
+
+		return err
+	}
+}
+
+func grpcLogFields(ctx context.Context, method string, start time.Time, err error) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	fields["method"] = method
+	fields["code"] = status.Code(err).String()
+	fields["latency"] = time.Since(start).Seconds()
+	fields["request_id"] = GetRequestID(ctx)
+	fields["trace_id"] = TraceID(ctx)
+
+	if err != nil {
+		fields["errors"] = err.Error()
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		fields["ip"] = p.Addr.String()
+	}
+
+	return fields
+}
+
+// RecoveryUnaryServerInterceptor turns panics into codes.Internal
+func RecoveryUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer grpcRecovery(info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor ...
+func RecoveryStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer grpcRecovery(info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func grpcRecovery(method string, err *error) {
+	if r := recover(); r != nil {
+		logrus.WithField("error", r).
+			WithField("method", method).
+			WithField("stack", string(debug.Stack())).
+			Error("Error on grpc recovery interceptor")
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// ErrorUnaryServerInterceptor translates *Error, including the ones
+// registered in DefaultErrorRules and DefaultErrorMapping, into grpc status
+// errors
+func ErrorUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, GRPCError(err)
+	}
+}
+
+// ErrorStreamServerInterceptor ...
+func ErrorStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return GRPCError(handler(srv, ss))
+	}
+}
+
+// GRPCError converts err into a grpc status error
+func GRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if errMapping := ResolveMappedError(err); errMapping != nil {
+		err = errMapping
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	message := e.Key
+	if len(e.Messages) > 0 {
+		message = strings.Join(e.Messages, "\n")
+	}
+
+	return status.Error(GRPCCode(e.Code), message)
+}
+
+// GRPCCode maps an HTTP status code to the equivalent grpc code
+func GRPCCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case 0, http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	if httpStatus >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+
+	return codes.InvalidArgument
+}
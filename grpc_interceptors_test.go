@@ -0,0 +1,102 @@
+package grok_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testUnaryInfo = &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Ping"}
+
+func TestGRPCErrorInterceptor(t *testing.T) {
+	interceptor := grok.ErrorUnaryServerInterceptor()
+
+	var items = []struct {
+		err      error
+		expected codes.Code
+	}{
+		{grok.NewError(http.StatusNotFound, "NOT_FOUND", "customer not found"), codes.NotFound},
+		{grok.NewError(http.StatusForbidden, "FORBIDDEN"), codes.PermissionDenied},
+		{fmt.Errorf("loading customer: %w", grok.NewError(http.StatusNotFound, "NOT_FOUND")), codes.NotFound},
+		{grok.NewError(0, "INVALID"), codes.InvalidArgument},
+		{grok.NewError(http.StatusBadGateway, "BAD_GATEWAY"), codes.Internal},
+		{status.Error(codes.Aborted, "aborted"), codes.Aborted},
+		{errors.New("unexpected"), codes.Internal},
+		{nil, codes.OK},
+	}
+
+	for _, item := range items {
+		_, err := interceptor(context.Background(), nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, item.err
+		})
+		assert.Equal(t, item.expected, status.Code(err))
+	}
+
+	_, err := interceptor(context.Background(), nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, grok.NewError(http.StatusNotFound, "NOT_FOUND", "customer not found")
+	})
+	assert.Equal(t, "customer not found", status.Convert(err).Message())
+}
+
+func TestGRPCRecoveryInterceptor(t *testing.T) {
+	interceptor := grok.RecoveryUnaryServerInterceptor()
+
+	_, err := interceptor(context.Background(), nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCRequestIDInterceptor(t *testing.T) {
+	interceptor := grok.RequestIDUnaryServerInterceptor()
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(grok.GRPCRequestIDMetadata, "incoming-id"))
+
+	_, err := interceptor(ctx, nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, "incoming-id", grok.GetRequestID(ctx))
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	_, err = interceptor(context.Background(), nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.NotEmpty(t, grok.GetRequestID(ctx))
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{"id\nforged=entry", strings.Repeat("a", 1000)} {
+		ctx = metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(grok.GRPCRequestIDMetadata, invalid))
+
+		_, err = interceptor(ctx, nil, testUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+			assert.NotEqual(t, invalid, grok.GetRequestID(ctx))
+			assert.NotEmpty(t, grok.GetRequestID(ctx))
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	}
+}
+
+func TestGRPCLoggingInterceptor(t *testing.T) {
+	interceptor := grok.LoggingUnaryServerInterceptor([]string{"secret"})
+
+	resp, err := interceptor(context.Background(), map[string]string{"secret": "value"}, testUnaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "pong", nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", resp)
+}
@@ -2,6 +2,7 @@ package grok
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -67,6 +68,13 @@ func WithRedis() HealtzOption {
 	}
 }
 
+// WithCheck adds a custom check
+func WithCheck(check func(*Healthz) error) HealtzOption {
+	return func(h *Healthz) {
+		h.checks = append(h.checks, check)
+	}
+}
+
 // WithHealthzSettings ...
 func WithHealthzSettings(s *Settings) HealtzOption {
 	return func(h *Healthz) {
@@ -127,7 +135,7 @@ func (h *Healthz) Healthz() error {
 		wg.Add(1)
 		go func(c func(*Healthz) error) {
 			defer wg.Done()
-			if err := c(h); err != nil {
+			if err := h.check(c); err != nil {
 				errCh <- err
 			}
 		}(check)
@@ -150,6 +158,22 @@ func (h *Healthz) Healthz() error {
 	return nil
 }
 
+// check runs c turning a panic, e.g. of a connection failing with
+// logrus.Panic, into an error so a dependency outage cannot crash the caller
+func (h *Healthz) check(c func(*Healthz) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if entry, ok := r.(*logrus.Entry); ok {
+				err = fmt.Errorf("%s: %v", entry.Message, entry.Data[logrus.ErrorKey])
+				return
+			}
+			err = fmt.Errorf("health check panic: %v", r)
+		}
+	}()
+
+	return c(h)
+}
+
 // HTTP ...
 func (h *Healthz) HTTP() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
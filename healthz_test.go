@@ -22,3 +22,17 @@ func TestHealthz(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestHealthzFailingDependency(t *testing.T) {
+	settings := &grok.Settings{Redis: &grok.RedisSettings{ConnectionString: "127.0.0.1:1"}}
+
+	healthz := grok.NewHealthz(
+		grok.WithRedis(),
+		grok.WithHealthzSettings(settings))
+
+	var err error
+	assert.NotPanics(t, func() {
+		err = healthz.Healthz()
+	})
+	assert.ErrorContains(t, err, "Error pinging Redis")
+}
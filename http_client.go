@@ -0,0 +1,39 @@
+package grok
+
+import (
+	"net/http"
+)
+
+// newHTTPClient returns the client used by grok outbound calls
+func newHTTPClient(name string) *http.Client {
+	return &http.Client{
+		Transport: newTransport(name),
+	}
+}
+
+// newTransport instruments http.DefaultTransport with metrics and tracing
+// and forwards the request id
+func newTransport(name string) http.RoundTripper {
+	return DefaultMetrics.Transport(name, tracingTransport(requestIDTransport(http.DefaultTransport)))
+}
+
+// requestIDTransport sets the X-Request-Id header from the request context
+func requestIDTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestID := GetRequestID(req.Context())
+		if requestID == "" || req.Header.Get(RequestIDHeader) != "" {
+			return next.RoundTrip(req)
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, requestID)
+
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,430 @@
+package grok
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// IdempotencyKeyHeader ...
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL        = 24 * time.Hour
+	defaultIdempotencyLockTTL    = 30 * time.Second
+	defaultIdempotencyCollection = "idempotency_keys"
+	defaultIdempotencyPrefix     = "idempotency"
+)
+
+// IdempotencyRecord holds the outcome of a request executed with an
+// Idempotency-Key
+type IdempotencyRecord struct {
+	Key         string      `bson:"_id" json:"key"`
+	Fingerprint string      `bson:"fingerprint" json:"fingerprint"`
+	InProgress  bool        `bson:"in_progress" json:"in_progress"`
+	Status      int         `bson:"status" json:"status"`
+	Header      http.Header `bson:"header" json:"header"`
+	Body        []byte      `bson:"body" json:"body"`
+	ExpiresAt   time.Time   `bson:"expires_at" json:"expires_at"`
+}
+
+// IdempotencyStore keeps idempotency records
+type IdempotencyStore interface {
+	// Begin reserves the key for the lock ttl. When the key already exists
+	// the stored record is returned and created is false. Expired
+	// reservations, left behind by crashed requests, are taken over.
+	Begin(ctx context.Context, key string, fingerprint string) (record *IdempotencyRecord, created bool, err error)
+	// Complete stores the captured response for the full ttl
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	// Release removes the key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// CreateIdempotencyStore creates the store configured in settings: mongo by
+// default, redis or in-memory when fake.
+func CreateIdempotencyStore(settings *Settings) IdempotencyStore {
+	s := settings.Idempotency
+	if s == nil {
+		s = &IdempotencySettings{}
+	}
+
+	ttl := defaultIdempotencyTTL
+	if s.TTL > 0 {
+		ttl = time.Duration(s.TTL) * time.Second
+	}
+
+	lockTTL := defaultIdempotencyLockTTL
+	if s.LockTTL > 0 {
+		lockTTL = time.Duration(s.LockTTL) * time.Second
+	}
+
+	if s.Fake {
+		return NewMemoryIdempotencyStore(ttl, lockTTL)
+	}
+
+	if s.Backend == "redis" {
+		return NewRedisIdempotencyStore(NewRedisConnection(settings.Redis.ConnectionString), defaultIdempotencyPrefix, ttl, lockTTL)
+	}
+
+	collection := s.Collection
+	if collection == "" {
+		collection = defaultIdempotencyCollection
+	}
+
+	client := NewMongoConnection(settings.Mongo.ConnectionString, settings.Mongo.CaFilePath)
+
+	return NewMongoIdempotencyStore(client.Database(settings.Mongo.Database).Collection(collection), ttl, lockTTL)
+}
+
+// IdempotencyMiddleware executes mutating requests carrying an
+// Idempotency-Key at most once. Retries with the same body replay the stored
+// response, retries while the first request is running get 409 and reusing
+// the key with a different body gets 422. Responses with status 5xx are not
+// stored so the request can be retried.
+func IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+
+		if idempotencyKey == "" || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		bodyCopy := new(bytes.Buffer)
+		if _, err := io.Copy(bodyCopy, c.Request.Body); err != nil {
+			logrus.WithError(err).
+				WithField("key", idempotencyKey).
+				Error("error reading idempotent request body")
+			abortWithError(c, http.StatusBadRequest,
+				NewError(http.StatusBadRequest, "INVALID_BODY", "error reading request body"))
+			return
+		}
+		bodyData := bodyCopy.Bytes()
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(bodyData))
+
+		ctx := c.Request.Context()
+		key := idempotencyStoreKey(c, idempotencyKey)
+		fingerprint := idempotencyFingerprint(c, bodyData)
+
+		record, created, err := store.Begin(ctx, key, fingerprint)
+		if err != nil {
+			logrus.WithError(err).
+				WithField("key", idempotencyKey).
+				Error("error reserving idempotency key")
+			abortWithError(c, http.StatusServiceUnavailable,
+				NewError(http.StatusServiceUnavailable, "IDEMPOTENCY_UNAVAILABLE", "idempotency store unavailable"))
+			return
+		}
+
+		if !created {
+			replayIdempotencyRecord(c, record, fingerprint)
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				if err := store.Release(context.Background(), key); err != nil {
+					logrus.WithError(err).
+						WithField("key", idempotencyKey).
+						Error("error releasing idempotency key")
+				}
+			}
+		}()
+
+		// headers set by the outer middleware, e.g. cors, are set again on
+		// replays and must not be stored
+		outer := c.Writer.Header().Clone()
+
+		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		c.Writer = blw
+
+		c.Next()
+
+		if blw.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		record.InProgress = false
+		record.Status = blw.Status()
+		record.Header = idempotencyHeader(outer, blw.Header())
+		record.Body = blw.body.Bytes()
+
+		if err := store.Complete(context.Background(), record); err != nil {
+			logrus.WithError(err).
+				WithField("key", idempotencyKey).
+				Error("error storing idempotent response")
+			return
+		}
+
+		completed = true
+	}
+}
+
+func replayIdempotencyRecord(c *gin.Context, record *IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		abortWithError(c, http.StatusUnprocessableEntity,
+			NewError(http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+				"idempotency key already used with a different request"))
+		return
+	}
+
+	if record.InProgress {
+		abortWithError(c, http.StatusConflict,
+			NewError(http.StatusConflict, "IDEMPOTENCY_KEY_IN_PROGRESS",
+				"a request with this idempotency key is in progress"))
+		return
+	}
+
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = append([]string{}, values...)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// idempotencyHeader copies the response headers worth replaying, the ones
+// the handler added or changed after the outer middleware ran
+func idempotencyHeader(outer http.Header, header http.Header) http.Header {
+	result := http.Header{}
+	for name, values := range header {
+		if name == "Request-Id" || name == "Set-Cookie" {
+			continue
+		}
+		if previous, found := outer[name]; found && reflect.DeepEqual(previous, values) {
+			continue
+		}
+		result[name] = append([]string{}, values...)
+	}
+	return result
+}
+
+// idempotencyStoreKey scopes the key to the caller so clients cannot collide
+func idempotencyStoreKey(c *gin.Context, key string) string {
+	return c.GetString("sub") + ":" + c.GetHeader(CurrentIdentityHeader) + ":" + key
+}
+
+func idempotencyFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte(c.Request.URL.RequestURI()))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func newIdempotencyRecord(key string, fingerprint string, ttl time.Duration) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		InProgress:  true,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+}
+
+// MongoIdempotencyStore ...
+type MongoIdempotencyStore struct {
+	collection *mongo.Collection
+	ttl        time.Duration
+	lockTTL    time.Duration
+}
+
+// NewMongoIdempotencyStore creates the store and a TTL index on expires_at
+func NewMongoIdempotencyStore(collection *mongo.Collection, ttl time.Duration, lockTTL time.Duration) *MongoIdempotencyStore {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	if err != nil {
+		logrus.WithError(err).Error("error creating idempotency ttl index")
+	}
+
+	return &MongoIdempotencyStore{collection: collection, ttl: ttl, lockTTL: lockTTL}
+}
+
+// Begin ...
+func (s *MongoIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string) (*IdempotencyRecord, bool, error) {
+	record := newIdempotencyRecord(key, fingerprint, s.lockTTL)
+
+	_, err := s.collection.InsertOne(ctx, record)
+	if err == nil {
+		return record, true, nil
+	}
+
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	existing := new(IdempotencyRecord)
+	if err := s.collection.FindOne(ctx, bson.M{"_id": key}).Decode(existing); err != nil {
+		return nil, false, err
+	}
+
+	// the ttl monitor runs periodically, expired records and abandoned
+	// reservations may still be there
+	if existing.ExpiresAt.Before(time.Now()) {
+		if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": key, "expires_at": existing.ExpiresAt}); err != nil {
+			return nil, false, err
+		}
+		return s.Begin(ctx, key, fingerprint)
+	}
+
+	return existing, false, nil
+}
+
+// Complete ...
+func (s *MongoIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	record.ExpiresAt = time.Now().Add(s.ttl)
+
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": record.Key}, record)
+	return err
+}
+
+// Release ...
+func (s *MongoIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// RedisIdempotencyStore ...
+type RedisIdempotencyStore struct {
+	client  *redis.Client
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewRedisIdempotencyStore ...
+func NewRedisIdempotencyStore(client *redis.Client, prefix string, ttl time.Duration, lockTTL time.Duration) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client, prefix: prefix, ttl: ttl, lockTTL: lockTTL}
+}
+
+func (s *RedisIdempotencyStore) key(key string) string {
+	return s.prefix + ":" + key
+}
+
+// Begin ...
+func (s *RedisIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string) (*IdempotencyRecord, bool, error) {
+	record := newIdempotencyRecord(key, fingerprint, s.lockTTL)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created, err := s.client.SetNX(ctx, s.key(key), data, s.lockTTL).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		return record, true, nil
+	}
+
+	data, err = s.client.Get(ctx, s.key(key)).Bytes()
+	if err == redis.Nil {
+		return s.Begin(ctx, key, fingerprint)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing := new(IdempotencyRecord)
+	if err := json.Unmarshal(data, existing); err != nil {
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+// Complete ...
+func (s *RedisIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	record.ExpiresAt = time.Now().Add(s.ttl)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, s.key(record.Key), data, s.ttl).Err()
+}
+
+// Release ...
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.key(key)).Err()
+}
+
+// MemoryIdempotencyStore keeps the records in process. It is meant for tests.
+type MemoryIdempotencyStore struct {
+	mutex   sync.Mutex
+	records map[string]IdempotencyRecord
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewMemoryIdempotencyStore ...
+func NewMemoryIdempotencyStore(ttl time.Duration, lockTTL time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]IdempotencyRecord{}, ttl: ttl, lockTTL: lockTTL}
+}
+
+// Begin ...
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string) (*IdempotencyRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, ok := s.records[key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, false, nil
+	}
+
+	record := newIdempotencyRecord(key, fingerprint, s.lockTTL)
+	s.records[key] = *record
+
+	return record, true, nil
+}
+
+// Complete ...
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record.ExpiresAt = time.Now().Add(s.ttl)
+	s.records[record.Key] = *record
+	return nil
+}
+
+// Release ...
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
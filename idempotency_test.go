@@ -0,0 +1,165 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func idempotentRequest(engine *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/transfers", strings.NewReader(body))
+	req.Header.Set(grok.IdempotencyKeyHeader, key)
+	engine.ServeHTTP(response, req)
+	return response
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var executions int32
+
+	engine := gin.New()
+	engine.Use(grok.IdempotencyMiddleware(grok.CreateIdempotencyStore(&grok.Settings{
+		Idempotency: &grok.IdempotencySettings{Fake: true},
+	})))
+	engine.POST("/transfers", func(c *gin.Context) {
+		n := atomic.AddInt32(&executions, 1)
+		c.Header("Location", "/transfers/1")
+		c.JSON(http.StatusCreated, gin.H{"execution": n})
+	})
+
+	response := idempotentRequest(engine, "key-1", `{"amount":10}`)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.JSONEq(t, `{"execution":1}`, response.Body.String())
+
+	response = idempotentRequest(engine, "key-1", `{"amount":10}`)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.JSONEq(t, `{"execution":1}`, response.Body.String())
+	assert.Equal(t, "/transfers/1", response.Header().Get("Location"))
+	assert.Equal(t, "true", response.Header().Get(grok.IdempotentReplayedHeader))
+
+	response = idempotentRequest(engine, "key-1", `{"amount":20}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	response = idempotentRequest(engine, "key-2", `{"amount":10}`)
+	assert.JSONEq(t, `{"execution":2}`, response.Body.String())
+
+	response = idempotentRequest(engine, "", `{"amount":10}`)
+	assert.JSONEq(t, `{"execution":3}`, response.Body.String())
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&executions))
+}
+
+func TestIdempotencyMiddlewareInProgress(t *testing.T) {
+	release := make(chan bool)
+	started := make(chan bool)
+
+	engine := gin.New()
+	engine.Use(grok.IdempotencyMiddleware(grok.NewMemoryIdempotencyStore(time.Hour, time.Minute)))
+	engine.POST("/transfers", func(c *gin.Context) {
+		started <- true
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- idempotentRequest(engine, "key", "{}")
+	}()
+
+	<-started
+	assert.Equal(t, http.StatusConflict, idempotentRequest(engine, "key", "{}").Code)
+
+	release <- true
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyMiddlewareServerError(t *testing.T) {
+	var executions int32
+
+	engine := gin.New()
+	engine.Use(grok.IdempotencyMiddleware(grok.NewMemoryIdempotencyStore(time.Hour, time.Minute)))
+	engine.POST("/transfers", func(c *gin.Context) {
+		if atomic.AddInt32(&executions, 1) == 1 {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusBadGateway, idempotentRequest(engine, "key", "{}").Code)
+	assert.Equal(t, http.StatusCreated, idempotentRequest(engine, "key", "{}").Code)
+}
+
+func TestIdempotencyMiddlewareReplayHeaders(t *testing.T) {
+	engine := gin.New()
+	engine.Use(
+		grok.SecurityHeadersMiddleware(nil, nil),
+		grok.CORSWithSettings(&grok.CORSSettings{AllowedOrigins: []string{"https://app.contbank.com"}}),
+		grok.RateLimitMiddleware(grok.NewMemoryRateLimitStore(), &grok.RateLimit{Limit: 10, Period: 60}),
+		grok.IdempotencyMiddleware(grok.NewMemoryIdempotencyStore(time.Hour, time.Minute)))
+	engine.POST("/transfers", func(c *gin.Context) {
+		c.Header("Location", "/transfers/1")
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/transfers", strings.NewReader("{}"))
+		req.Header.Set(grok.IdempotencyKeyHeader, "key")
+		req.Header.Set("Origin", "https://app.contbank.com")
+		engine.ServeHTTP(response, req)
+		return response
+	}
+
+	first := request()
+	replayed := request()
+
+	assert.Equal(t, "true", replayed.Header().Get(grok.IdempotentReplayedHeader))
+	assert.Equal(t, []string{"https://app.contbank.com"}, replayed.Header().Values("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, replayed.Header().Values("Vary"))
+	assert.Equal(t, []string{"nosniff"}, replayed.Header().Values("X-Content-Type-Options"))
+	assert.Equal(t, []string{"/transfers/1"}, replayed.Header().Values("Location"))
+	assert.Equal(t, first.Header().Values("Content-Type"), replayed.Header().Values("Content-Type"))
+	assert.Equal(t, []string{"9"}, first.Header().Values("RateLimit-Remaining"))
+	assert.Equal(t, []string{"8"}, replayed.Header().Values("RateLimit-Remaining"))
+}
+
+func TestIdempotencyStoreAbandonedReservation(t *testing.T) {
+	store := grok.NewMemoryIdempotencyStore(time.Hour, 50*time.Millisecond)
+
+	// a request that crashed before completing leaves its reservation behind
+	_, created, err := store.Begin(context.Background(), "abandoned", "fingerprint")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	existing, created, err := store.Begin(context.Background(), "abandoned", "fingerprint")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.True(t, existing.InProgress)
+
+	time.Sleep(100 * time.Millisecond)
+
+	record, created, err := store.Begin(context.Background(), "abandoned", "fingerprint")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	record.InProgress = false
+	record.Status = http.StatusCreated
+	assert.NoError(t, store.Complete(context.Background(), record))
+	assert.True(t, record.ExpiresAt.After(time.Now().Add(time.Minute)))
+
+	time.Sleep(100 * time.Millisecond)
+
+	existing, created, err = store.Begin(context.Background(), "abandoned", "fingerprint")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, http.StatusCreated, existing.Status)
+}
@@ -36,7 +36,8 @@ func NewIntraAuthentication(session Session) *IntraAuthentication {
 	return &IntraAuthentication{
 		session: session,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: newTransport("intra_authentication"),
+			Timeout:   30 * time.Second,
 		},
 	}
 }
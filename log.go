@@ -14,3 +14,19 @@ func Init() {
 
 	logrus.SetLevel(logrus.DebugLevel)
 }
+
+// ApplyLogLevel sets the logrus level from the log settings, keeping the
+// current level when none is configured
+func ApplyLogLevel(settings *LogSettings) {
+	if settings == nil || settings.Level == "" {
+		return
+	}
+
+	level, err := logrus.ParseLevel(settings.Level)
+	if err != nil {
+		logrus.WithError(err).Error("invalid log level")
+		return
+	}
+
+	logrus.SetLevel(level)
+}
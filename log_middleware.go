@@ -26,19 +26,51 @@ func (w *bodyLogWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+const (
+	// RequestIDHeader is the default header carrying an inbound request id
+	// and the header set on grok outbound calls
+	RequestIDHeader = "X-Request-Id"
+	// RequestIDAttribute is the SNS message attribute carrying the request id
+	RequestIDAttribute = "Request-Id"
+
+	maxRequestIDLength = 128
+)
+
+// LogOption ...
+type LogOption func(*logOptions)
+
+type logOptions struct {
+	requestIDHeader string
+}
+
+// WithRequestIDHeader sets the header an inbound request id is read from
+func WithRequestIDHeader(header string) LogOption {
+	return func(o *logOptions) {
+		if header != "" {
+			o.requestIDHeader = header
+		}
+	}
+}
+
 //LogMiddleware ...
-func LogMiddleware(restricteds []string) gin.HandlerFunc {
+func LogMiddleware(restricteds []string, opts ...LogOption) gin.HandlerFunc {
+	options := &logOptions{requestIDHeader: RequestIDHeader}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	return func(c *gin.Context) {
 		defer recovery(c)
 		defer c.Request.Body.Close()
 
-		requestID := uuid.New()
+		requestID := inboundRequestID(c.GetHeader(options.requestIDHeader))
 
 		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		blw.Header().Set("Request-Id", requestID.String())
+		blw.Header().Set("Request-Id", requestID)
 		c.Writer = blw
 
-		c.Set("Request-Id", requestID.String())
+		c.Set("Request-Id", requestID)
+		c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), requestID))
 
 		now := time.Now()
 		req := request(c, restricteds)
@@ -53,7 +85,8 @@ func LogMiddleware(restricteds []string) gin.HandlerFunc {
 		fields["errors"] = c.Errors
 		fields["ip"] = c.ClientIP()
 		fields["latency"] = elapsed.Seconds()
-		fields["request_id"] = requestID.String()
+		fields["request_id"] = requestID
+		fields["trace_id"] = TraceID(c.Request.Context())
 		fields["response"] = response(blw, restricteds)
 
 		logrus.WithFields(fields).Infof(
@@ -65,6 +98,22 @@ func LogMiddleware(restricteds []string) gin.HandlerFunc {
 	}
 }
 
+// inboundRequestID accepts ids sent by the caller, generating one when it is
+// missing or not a printable token
+func inboundRequestID(requestID string) string {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return uuid.New().String()
+	}
+
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' {
+			return uuid.New().String()
+		}
+	}
+
+	return requestID
+}
+
 func request(context *gin.Context, restricteds []string) interface{} {
 	r := make(map[string]interface{})
 
@@ -111,7 +160,7 @@ func recovery(c *gin.Context) {
 			WithField("stack", string(debug.Stack())).
 			Error("Error on logging middleware")
 		internalServerError := NewError(http.StatusInternalServerError, "internal server error")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, internalServerError)
+		abortWithError(c, http.StatusInternalServerError, internalServerError)
 	}
 }
 
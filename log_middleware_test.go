@@ -0,0 +1,67 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestIDEngine(opts ...grok.LogOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(grok.LogMiddleware(nil, opts...))
+	engine.GET("/request-id", func(c *gin.Context) {
+		c.String(http.StatusOK, grok.GetRequestID(c.Request.Context()))
+	})
+	return engine
+}
+
+func TestLogMiddlewareRequestID(t *testing.T) {
+	var items = []struct {
+		header   string
+		value    string
+		opts     []grok.LogOption
+		expected string
+	}{
+		{grok.RequestIDHeader, "gateway-id", nil, "gateway-id"},
+		{"X-Correlation-Id", "gateway-id", []grok.LogOption{grok.WithRequestIDHeader("X-Correlation-Id")}, "gateway-id"},
+		{"X-Correlation-Id", "gateway-id", nil, ""},
+		{grok.RequestIDHeader, "invalid id", nil, ""},
+		{grok.RequestIDHeader, strings.Repeat("a", 200), nil, ""},
+	}
+
+	for _, item := range items {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/request-id", nil)
+		req.Header.Set(item.header, item.value)
+
+		requestIDEngine(item.opts...).ServeHTTP(response, req)
+
+		requestID := response.Body.String()
+		assert.NotEmpty(t, requestID)
+		assert.Equal(t, requestID, response.Header().Get("Request-Id"))
+		if item.expected != "" {
+			assert.Equal(t, item.expected, requestID)
+		} else {
+			assert.NotEqual(t, item.value, requestID)
+		}
+	}
+}
+
+func TestOutboundRequestID(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gateway-id", r.Header.Get(grok.RequestIDHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	authorize := grok.CreateAuthorize(&grok.InternalAuth{URLs: []*string{grok.String(upstream.URL)}}).(grok.PermissionVerifier)
+
+	ctx := grok.ContextWithRequestID(context.Background(), "gateway-id")
+	assert.NoError(t, authorize.VerifyPermissions(ctx, "Bearer token", "12345678909", []string{"read:customers"}))
+}
@@ -25,7 +25,7 @@ func SetMaxBodyBytesMiddleware(maxMegaBytes int64) gin.HandlerFunc {
 		if err != nil {
 			logrus.WithField("error", err).Error("error on max body middleware")
 			entityTooLarge := NewError(http.StatusRequestEntityTooLarge, "payload too large")
-			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, entityTooLarge)
+			abortWithError(c, http.StatusRequestEntityTooLarge, entityTooLarge)
 
 			return
 		}
@@ -0,0 +1,166 @@
+package grok
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// MetricsPath ...
+	MetricsPath = "/metrics"
+)
+
+var (
+	// DefaultMetrics is shared by the API, subscribers, producers and
+	// outbound clients so every service exposes the same series.
+	DefaultMetrics = NewMetrics(prometheus.NewRegistry())
+)
+
+// Metrics ...
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	httpResponseSize  *prometheus.HistogramVec
+	clientRequests    *prometheus.CounterVec
+	clientDuration    *prometheus.HistogramVec
+	messagesPublished *prometheus.CounterVec
+	messagesConsumed  *prometheus.CounterVec
+	messageDuration   *prometheus.HistogramVec
+}
+
+// NewMetrics registers grok collectors into the given registry
+func NewMetrics(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		Registry: registry,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests handled.",
+		}, []string{"method", "path", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path", "status"}),
+		httpResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_response_size_bytes",
+			Help:    "HTTP response size in bytes.",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 6),
+		}, []string{"method", "path", "status"}),
+		clientRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "Total number of outbound HTTP requests.",
+		}, []string{"client", "method", "status"}),
+		clientDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Outbound HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"client", "method", "status"}),
+		messagesPublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "message_broker_published_total",
+			Help: "Total number of messages published.",
+		}, []string{"topic", "result"}),
+		messagesConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "message_broker_consumed_total",
+			Help: "Total number of messages consumed.",
+		}, []string{"subscriber", "result"}),
+		messageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "message_broker_handler_duration_seconds",
+			Help:    "Message handler latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"subscriber"}),
+	}
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpResponseSize,
+		m.clientRequests,
+		m.clientDuration,
+		m.messagesPublished,
+		m.messagesConsumed,
+		m.messageDuration,
+	)
+
+	return m
+}
+
+// Handler exposes the registry in Prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware records request count, latency and response size labelled by
+// route, method and status
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unknown"
+		}
+
+		status := strconv.Itoa(c.Writer.Status())
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+
+		m.httpRequests.WithLabelValues(c.Request.Method, path, status).Inc()
+		m.httpDuration.WithLabelValues(c.Request.Method, path, status).Observe(time.Since(now).Seconds())
+		m.httpResponseSize.WithLabelValues(c.Request.Method, path, status).Observe(float64(size))
+	}
+}
+
+// Transport instruments outbound requests made through next
+func (m *Metrics) Transport(client string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		now := time.Now()
+
+		resp, err := next.RoundTrip(req)
+
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+
+		m.clientRequests.WithLabelValues(client, req.Method, status).Inc()
+		m.clientDuration.WithLabelValues(client, req.Method, status).Observe(time.Since(now).Seconds())
+
+		return resp, err
+	})
+}
+
+// ObservePublish ...
+func (m *Metrics) ObservePublish(topic string, err error) {
+	m.messagesPublished.WithLabelValues(topic, metricsResult(err)).Inc()
+}
+
+// ObserveConsume ...
+func (m *Metrics) ObserveConsume(subscriber string, elapsed time.Duration, err error) {
+	m.messagesConsumed.WithLabelValues(subscriber, metricsResult(err)).Inc()
+	m.messageDuration.WithLabelValues(subscriber).Observe(elapsed.Seconds())
+}
+
+func metricsResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
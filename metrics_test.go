@@ -0,0 +1,74 @@
+package grok_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type metricsContainer struct{}
+
+func (c *metricsContainer) Controllers() []grok.APIController {
+	return []grok.APIController{c}
+}
+
+func (c *metricsContainer) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/customers/:id", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"id": ctx.Param("id")})
+	})
+}
+
+func (c *metricsContainer) Close() error {
+	return nil
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := grok.New(
+		grok.WithSettings(&grok.Settings{API: &grok.APISettings{}}),
+		grok.WithMetrics(),
+		grok.WithContainer(&metricsContainer{}))
+
+	response := httptest.NewRecorder()
+	server.Engine.ServeHTTP(response, httptest.NewRequest("GET", "/customers/123", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	server.Engine.ServeHTTP(response, httptest.NewRequest("GET", grok.MetricsPath, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	body := response.Body.String()
+	assert.Contains(t, body, `http_server_requests_total{method="GET",path="/customers/:id",status="200"} 1`)
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{method="GET",path="/customers/:id",status="200"} 1`)
+	assert.Contains(t, body, `http_server_response_size_bytes_count{method="GET",path="/customers/:id",status="200"} 1`)
+}
+
+func TestMetricsTransport(t *testing.T) {
+	metrics := grok.NewMetrics(prometheus.NewRegistry())
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: metrics.Transport("upstream", nil)}
+	resp, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	metrics.ObservePublish("topic", nil)
+
+	engine := gin.New()
+	engine.GET("/metrics", metrics.Handler())
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Contains(t, string(body), `http_client_requests_total{client="upstream",method="GET",status="418"} 1`)
+	assert.Contains(t, string(body), `message_broker_published_total{result="success",topic="topic"} 1`)
+}
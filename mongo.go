@@ -18,12 +18,14 @@ func NewMongoConnection(connectionString string, caFilePath *string) *mongo.Clie
 	var client *mongo.Client
 	var err error
 
+	clientOptions := options.Client().ApplyURI(connectionString).SetMonitor(MongoTracingMonitor())
+
 	if caFilePath != nil {
 		tlsConfig := getCustomTLSConfig(*caFilePath)
 
-		client, err = mongo.NewClient(options.Client().ApplyURI(connectionString).SetTLSConfig(tlsConfig))
+		client, err = mongo.NewClient(clientOptions.SetTLSConfig(tlsConfig))
 	} else {
-		client, err = mongo.NewClient(options.Client().ApplyURI(connectionString))
+		client, err = mongo.NewClient(clientOptions)
 	}
 
 	if err != nil {
@@ -1,7 +1,9 @@
 package grok_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/contbank/grok"
 	"github.com/stretchr/testify/assert"
@@ -58,3 +60,29 @@ func (s *MongoTestSuite) TestIsNotFoundError() {
 	err = grok.NewError(100, "SOME_ERROR", "mongo : no documents in result")
 	s.assert.True(grok.IsNotFoundError(err))
 }
+
+func (s *MongoTestSuite) TestIdempotencyStore() {
+	client := grok.NewMongoConnection(s.settings.Mongo.ConnectionString, nil)
+	store := grok.NewMongoIdempotencyStore(client.Database(s.settings.Mongo.Database).Collection("idempotency_keys"), time.Minute, time.Minute)
+	key := grok.GeneratorIDBase(10)
+
+	record, created, err := store.Begin(context.Background(), key, "fingerprint")
+	s.assert.NoError(err)
+	s.assert.True(created)
+
+	existing, created, err := store.Begin(context.Background(), key, "fingerprint")
+	s.assert.NoError(err)
+	s.assert.False(created)
+	s.assert.True(existing.InProgress)
+
+	record.InProgress = false
+	record.Status = 201
+	record.Body = []byte("{}")
+	s.assert.NoError(store.Complete(context.Background(), record))
+
+	existing, _, err = store.Begin(context.Background(), key, "fingerprint")
+	s.assert.NoError(err)
+	s.assert.Equal(201, existing.Status)
+
+	s.assert.NoError(store.Release(context.Background(), key))
+}
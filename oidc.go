@@ -0,0 +1,412 @@
+package grok
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// Auth0Provider validates tokens with the auth0 tenant and jwks settings
+	Auth0Provider = "auth0"
+	// OIDCProvider validates tokens of the configured OpenID Connect issuers
+	OIDCProvider = "oidc"
+
+	// OIDCDiscoveryPath ...
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+
+	defaultJWKSRefreshInterval = time.Hour
+	minJWKSRefreshInterval     = 10 * time.Second
+	tokenLeeway                = time.Minute
+)
+
+var (
+	// DefaultOIDCAlgorithms are the signature algorithms accepted when none
+	// is configured
+	DefaultOIDCAlgorithms = []string{string(jose.RS256), string(jose.ES256)}
+
+	errUnknownIssuer = errors.New("unknown token issuer")
+	errUnknownKey    = errors.New("unknown token key")
+	errMissingExpiry = errors.New("token has no expiration")
+)
+
+// OIDCAuthenticate validates tokens signed by the keys of OpenID Connect
+// issuers. Keys are discovered through the issuer discovery document and
+// refreshed in background.
+type OIDCAuthenticate struct {
+	memoryCache *cache.Cache
+	auth        *APIAuth
+	client      *http.Client
+	namespace   string
+	algorithms  []string
+	issuers     map[string]*oidcIssuer
+	options     *authenticateOptions
+}
+
+type oidcIssuer struct {
+	url      string
+	client   *http.Client
+	interval time.Duration
+
+	mutex      sync.RWMutex
+	jwksURI    string
+	keys       *jose.JSONWebKeySet
+	refreshed  time.Time
+	refreshing bool
+}
+
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// NewOIDCAuthenticate ...
+func NewOIDCAuthenticate(auth *APIAuth, cache *cache.Cache, opts ...AuthenticateOption) *OIDCAuthenticate {
+	a := &OIDCAuthenticate{
+		memoryCache: cache,
+		auth:        auth,
+		client:      newHTTPClient("oidc"),
+		namespace:   AuthClaimNamespace,
+		algorithms:  DefaultOIDCAlgorithms,
+		issuers:     map[string]*oidcIssuer{},
+		options:     newAuthenticateOptions(opts),
+	}
+
+	if auth.ClaimNamespace != "" {
+		a.namespace = auth.ClaimNamespace
+	}
+
+	if len(auth.Algorithms) > 0 {
+		a.algorithms = auth.Algorithms
+	}
+
+	interval := defaultJWKSRefreshInterval
+	if auth.JWKSRefreshInterval > 0 {
+		interval = time.Duration(auth.JWKSRefreshInterval) * time.Second
+	}
+
+	for _, issuer := range auth.Issuers {
+		a.issuers[normalizeIssuer(issuer)] = &oidcIssuer{
+			url:      normalizeIssuer(issuer),
+			client:   a.client,
+			interval: interval,
+		}
+	}
+
+	return a
+}
+
+// Middleware ...
+func (a *OIDCAuthenticate) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := a.ValidateToken(c.Request.Context(), c.Request.Header.Get("authorization"))
+
+		if err != nil {
+			c.Error(err)
+			abortWithStatus(c, http.StatusUnauthorized)
+			return
+		}
+
+		setKeys(c, claims)
+
+		c.Next()
+	}
+}
+
+// ValidateToken returns the claims of a valid bearer token with the claim
+// namespace replaced by AuthClaimNamespace
+func (a *OIDCAuthenticate) ValidateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	claims, err := a.validateToken(ctx, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.options.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (a *OIDCAuthenticate) validateToken(ctx context.Context, authorization string) (map[string]interface{}, error) {
+	raw, err := bearerToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	key := tokenCacheKey(raw)
+	if a.memoryCache != nil {
+		if claims, found := a.memoryCache.Get(key); found {
+			return claims.(map[string]interface{}), nil
+		}
+	}
+
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(token.Headers) != 1 || !contains(a.algorithms, token.Headers[0].Algorithm) {
+		return nil, fmt.Errorf("token algorithm not allowed")
+	}
+
+	unverified := jwt.Claims{}
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, err
+	}
+
+	issuer, found := a.issuers[normalizeIssuer(unverified.Issuer)]
+	if !found {
+		return nil, errUnknownIssuer
+	}
+
+	jwk, err := issuer.key(ctx, token.Headers[0].KeyID, token.Headers[0].Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := jwt.Claims{}
+	claims := map[string]interface{}{}
+	if err := token.Claims(jwk.Key, &registered, &claims); err != nil {
+		return nil, err
+	}
+
+	err = registered.ValidateWithLeeway(jwt.Expected{
+		Issuer: unverified.Issuer,
+		Time:   time.Now(),
+	}, tokenLeeway)
+	if err != nil {
+		return nil, err
+	}
+
+	// tokens without exp never expire, they are not accepted
+	if registered.Expiry == nil {
+		return nil, errMissingExpiry
+	}
+
+	if !a.validAudience(registered.Audience) {
+		return nil, jwt.ErrInvalidAudience
+	}
+
+	claims = a.normalizeClaims(claims)
+
+	if a.memoryCache != nil {
+		if ttl := time.Until(registered.Expiry.Time()); ttl > 0 {
+			a.memoryCache.Set(key, claims, ttl)
+		}
+	}
+
+	return claims, nil
+}
+
+// validAudience accepts tokens for any of the configured audiences, none
+// is accepted when no audience is configured
+func (a *OIDCAuthenticate) validAudience(audience jwt.Audience) bool {
+	for _, expected := range a.auth.Audience {
+		if audience.Contains(expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeClaims replaces the configured namespace by AuthClaimNamespace
+// so claims are set into the context the same way for every provider
+func (a *OIDCAuthenticate) normalizeClaims(claims map[string]interface{}) map[string]interface{} {
+	if a.namespace == AuthClaimNamespace {
+		return claims
+	}
+
+	result := make(map[string]interface{}, len(claims))
+	for key, value := range claims {
+		if strings.HasPrefix(key, a.namespace) {
+			key = AuthClaimNamespace + strings.TrimPrefix(key, a.namespace)
+		}
+		result[key] = value
+	}
+
+	return result
+}
+
+// key returns the issuer key kid, refreshing the keys when they are stale
+// in background or right away when kid is unknown
+func (i *oidcIssuer) key(ctx context.Context, kid string, algorithm string) (*jose.JSONWebKey, error) {
+	i.mutex.RLock()
+	keys, refreshed, refreshing := i.keys, i.refreshed, i.refreshing
+	i.mutex.RUnlock()
+
+	if keys == nil {
+		if err := i.refresh(ctx); err != nil {
+			return nil, err
+		}
+	} else if time.Since(refreshed) > i.interval && !refreshing {
+		i.refreshInBackground()
+	}
+
+	if jwk := i.find(kid, algorithm); jwk != nil {
+		return jwk, nil
+	}
+
+	i.mutex.RLock()
+	refreshed = i.refreshed
+	i.mutex.RUnlock()
+
+	if time.Since(refreshed) < i.minRefresh() {
+		return nil, errUnknownKey
+	}
+
+	if err := i.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	if jwk := i.find(kid, algorithm); jwk != nil {
+		return jwk, nil
+	}
+
+	return nil, errUnknownKey
+}
+
+// minRefresh limits the refreshes caused by unknown keys
+func (i *oidcIssuer) minRefresh() time.Duration {
+	if i.interval < minJWKSRefreshInterval {
+		return i.interval
+	}
+	return minJWKSRefreshInterval
+}
+
+func (i *oidcIssuer) find(kid string, algorithm string) *jose.JSONWebKey {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if i.keys == nil {
+		return nil
+	}
+
+	for _, jwk := range i.keys.Key(kid) {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if jwk.Algorithm != "" && jwk.Algorithm != algorithm {
+			continue
+		}
+		jwk := jwk
+		return &jwk
+	}
+
+	return nil
+}
+
+func (i *oidcIssuer) refreshInBackground() {
+	i.mutex.Lock()
+	if i.refreshing {
+		i.mutex.Unlock()
+		return
+	}
+	i.refreshing = true
+	i.mutex.Unlock()
+
+	go func() {
+		if err := i.refresh(context.Background()); err != nil {
+			logrus.WithError(err).
+				WithField("issuer", i.url).
+				Error("error refreshing jwks")
+		}
+	}()
+}
+
+// refresh discovers the jwks uri, once, and reloads the keys
+func (i *oidcIssuer) refresh(ctx context.Context) error {
+	defer func() {
+		i.mutex.Lock()
+		i.refreshing = false
+		i.mutex.Unlock()
+	}()
+
+	i.mutex.RLock()
+	jwksURI := i.jwksURI
+	i.mutex.RUnlock()
+
+	if jwksURI == "" {
+		discovery := &oidcDiscovery{}
+		if err := i.get(ctx, i.url+OIDCDiscoveryPath, discovery); err != nil {
+			return fmt.Errorf("error discovering issuer %s: %v", i.url, err)
+		}
+
+		if normalizeIssuer(discovery.Issuer) != i.url {
+			return fmt.Errorf("discovery issuer %s does not match %s", discovery.Issuer, i.url)
+		}
+
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("issuer %s has no jwks_uri", i.url)
+		}
+
+		jwksURI = discovery.JWKSURI
+	}
+
+	keys := &jose.JSONWebKeySet{}
+	if err := i.get(ctx, jwksURI, keys); err != nil {
+		return fmt.Errorf("error loading jwks of issuer %s: %v", i.url, err)
+	}
+
+	i.mutex.Lock()
+	i.jwksURI = jwksURI
+	i.keys = keys
+	i.refreshed = time.Now()
+	i.mutex.Unlock()
+
+	return nil
+}
+
+func (i *oidcIssuer) get(ctx context.Context, url string, dist interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(dist)
+}
+
+// bearerToken extracts the token of a "Bearer <token>" authorization
+func bearerToken(authorization string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(authorization), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") || strings.TrimSpace(parts[1]) == "" {
+		return "", ErrUnauthorized
+	}
+	return strings.TrimSpace(parts[1]), nil
+}
+
+// tokenCacheKey keys cached claims by a hash so tokens are not kept in
+// memory as is
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeIssuer(issuer string) string {
+	return strings.TrimRight(issuer, "/")
+}
@@ -0,0 +1,144 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/contbank/grok/groktest"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func newLocalIssuer(t *testing.T, opts ...groktest.LocalIssuerOption) *groktest.LocalIssuer {
+	issuer, err := groktest.NewLocalIssuer(opts...)
+	assert.NoError(t, err)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func authorization(t *testing.T, issuer *groktest.LocalIssuer, opts ...groktest.TokenOption) string {
+	authorization, err := issuer.Authorization(opts...)
+	assert.NoError(t, err)
+	return authorization
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	rsaIssuer := newLocalIssuer(t)
+	ecIssuer := newLocalIssuer(t,
+		groktest.WithIssuerAlgorithm(jose.ES256),
+		groktest.WithIssuerAudience("https://admin.contbank.com"))
+
+	auth := &grok.APIAuth{
+		Provider:       grok.OIDCProvider,
+		Issuers:        []string{rsaIssuer.Issuer(), ecIssuer.Server.URL},
+		Audience:       []string{rsaIssuer.Audience, ecIssuer.Audience},
+		ClaimNamespace: "https://contbank.com/claims/",
+	}
+
+	authenticate := grok.CreateAuthenticate(auth, cache.New(time.Minute, time.Minute)).(grok.TokenValidator)
+	ctx := context.Background()
+
+	result, err := authenticate.ValidateToken(ctx, authorization(t, rsaIssuer,
+		groktest.WithSubject("auth0|123"),
+		groktest.WithClaim("https://contbank.com/claims/stores", []string{"store"})))
+	assert.NoError(t, err)
+	assert.Equal(t, "auth0|123", result["sub"])
+	assert.Equal(t, []interface{}{"store"}, result[grok.AuthClaimNamespace+"stores"])
+
+	_, err = authenticate.ValidateToken(ctx, authorization(t, ecIssuer))
+	assert.NoError(t, err)
+
+	invalids := map[string]string{
+		"audience":   authorization(t, rsaIssuer, groktest.WithAudience("https://other.com")),
+		"expired":    authorization(t, rsaIssuer, groktest.WithExpiry(-time.Hour)),
+		"no expiry":  authorization(t, rsaIssuer, groktest.WithoutClaim("exp")),
+		"issuer":     authorization(t, rsaIssuer, groktest.WithClaim("iss", "https://unknown.com/")),
+		"forged":     authorization(t, ecIssuer, groktest.WithClaim("iss", rsaIssuer.Issuer())),
+		"not bearer": "Basic dXNlcjpwYXNz",
+		"malformed":  "Bearer token",
+	}
+
+	for name, authorization := range invalids {
+		_, err := authenticate.ValidateToken(ctx, authorization)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestOIDCAuthenticateWithoutAudience(t *testing.T) {
+	issuer := newLocalIssuer(t)
+
+	auth := issuer.OIDCAPIAuth()
+	auth.Audience = nil
+
+	authenticate := grok.CreateAuthenticate(auth, nil).(grok.TokenValidator)
+
+	_, err := authenticate.ValidateToken(context.Background(), authorization(t, issuer))
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthenticateAlgorithms(t *testing.T) {
+	issuer := newLocalIssuer(t, groktest.WithIssuerAlgorithm(jose.ES256))
+
+	auth := issuer.OIDCAPIAuth()
+	auth.Algorithms = []string{string(jose.RS256)}
+
+	_, err := grok.NewOIDCAuthenticate(auth, nil).ValidateToken(context.Background(), authorization(t, issuer))
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthenticateKeyRotation(t *testing.T) {
+	issuer := newLocalIssuer(t)
+
+	auth := issuer.OIDCAPIAuth()
+	auth.JWKSRefreshInterval = 1
+
+	authenticate := grok.NewOIDCAuthenticate(auth, nil)
+	ctx := context.Background()
+
+	previous := authorization(t, issuer)
+
+	_, err := authenticate.ValidateToken(ctx, previous)
+	assert.NoError(t, err)
+
+	assert.NoError(t, issuer.RotateKey())
+	rotated := authorization(t, issuer)
+
+	_, err = authenticate.ValidateToken(ctx, rotated)
+	assert.Error(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	_, err = authenticate.ValidateToken(ctx, rotated)
+	assert.NoError(t, err)
+
+	_, err = authenticate.ValidateToken(ctx, previous)
+	assert.NoError(t, err)
+}
+
+func TestOIDCAuthenticateMiddleware(t *testing.T) {
+	issuer := newLocalIssuer(t)
+	authenticate := grok.NewOIDCAuthenticate(issuer.OIDCAPIAuth(), nil)
+
+	engine := gin.New()
+	engine.GET("/", authenticate.Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sub": c.GetString("sub"), "stores": c.Request.Context().Value("stores")})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", authorization(t, issuer, groktest.WithSubject("auth0|123"), groktest.WithStores("store")))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"sub": "auth0|123", "stores": ["store"]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
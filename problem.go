@@ -0,0 +1,121 @@
+package grok
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ProblemContentType is the RFC 7807 media type
+	ProblemContentType = "application/problem+json"
+
+	problemJSONKey = "problem_json"
+)
+
+// Problem is an RFC 7807 problem document. Key is an extension member
+// carrying the grok error key.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Key      string       `json:"key,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ProblemJSONMiddleware makes grok render errors as problem documents for
+// the requests it handles
+func ProblemJSONMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(problemJSONKey, true)
+		c.Next()
+	}
+}
+
+// IsProblemJSON reports whether errors are rendered as problem documents
+func IsProblemJSON(c *gin.Context) bool {
+	return c.GetBool(problemJSONKey)
+}
+
+// Problem converts the error into a problem document
+func (e *Error) Problem(status int, instance string) *Problem {
+	problem := &Problem{
+		Type:     e.Type,
+		Title:    e.Title,
+		Status:   status,
+		Detail:   e.Detail,
+		Instance: e.Instance,
+		Key:      e.Key,
+		Errors:   e.Errors,
+	}
+
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(status)
+	}
+
+	if problem.Detail == "" {
+		problem.Detail = strings.Join(e.Messages, "; ")
+	}
+
+	if problem.Instance == "" {
+		problem.Instance = instance
+	}
+
+	return problem
+}
+
+// RenderError writes err as a problem document when enabled for the
+// request, otherwise in the legacy format
+func RenderError(c *gin.Context, status int, err *Error) {
+	if !IsProblemJSON(c) {
+		c.JSON(status, err)
+		return
+	}
+
+	c.Render(status, problemRender{problem: err.Problem(status, requestID(c))})
+}
+
+// abortWithError renders err and aborts the request
+func abortWithError(c *gin.Context, status int, err *Error) {
+	RenderError(c, status, err)
+	c.Abort()
+}
+
+// abortWithStatus keeps the legacy empty body, rendering a problem document
+// only when enabled for the request
+func abortWithStatus(c *gin.Context, status int) {
+	if !IsProblemJSON(c) {
+		c.AbortWithStatus(status)
+		return
+	}
+
+	abortWithError(c, status, NewError(status, strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))))
+}
+
+func requestID(c *gin.Context) string {
+	if requestID := c.GetString("Request-Id"); requestID != "" {
+		return requestID
+	}
+	return GetRequestID(c.Request.Context())
+}
+
+type problemRender struct {
+	problem *Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+}
@@ -0,0 +1,102 @@
+package grok_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func problemEngine(opts ...grok.APIOption) *gin.Engine {
+	opts = append(opts,
+		grok.WithSettings(&grok.Settings{API: &grok.APISettings{}}),
+		grok.WithContainer(&testContainer{}))
+
+	server := grok.New(opts...)
+
+	server.Engine.GET("/not-found", func(c *gin.Context) {
+		grok.ResolveError(c, grok.NewError(http.StatusNotFound, "CUSTOMER_NOT_FOUND", "customer not found"))
+	})
+	server.Engine.GET("/unexpected", func(c *gin.Context) {
+		grok.ResolveError(c, errors.New("unexpected"))
+	})
+	server.Engine.POST("/binding", func(c *gin.Context) {
+		body := struct {
+			Name string `json:"name" binding:"required"`
+		}{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			grok.BindingError(c, err)
+		}
+	})
+	server.Engine.GET("/private", grok.NewFakeAuthenticate(false, nil).Middleware(), func(c *gin.Context) {})
+	server.Engine.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	return server.Engine
+}
+
+func serveProblem(engine *gin.Engine, method string, path string) (*httptest.ResponseRecorder, *grok.Problem) {
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	req.Header.Set(grok.RequestIDHeader, "request-id")
+	engine.ServeHTTP(response, req)
+
+	problem := new(grok.Problem)
+	json.Unmarshal(response.Body.Bytes(), problem)
+	return response, problem
+}
+
+func TestProblemJSON(t *testing.T) {
+	engine := problemEngine(grok.WithProblemJSON())
+
+	response, problem := serveProblem(engine, "GET", "/not-found")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Equal(t, grok.ProblemContentType, response.Header().Get("Content-Type"))
+	assert.Equal(t, &grok.Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "customer not found",
+		Instance: "request-id",
+		Key:      "CUSTOMER_NOT_FOUND",
+	}, problem)
+
+	response, problem = serveProblem(engine, "GET", "/unexpected")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+
+	response, problem = serveProblem(engine, "POST", "/binding")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, "name", problem.Errors[0].Field)
+	assert.Equal(t, "required", problem.Errors[0].Tag)
+
+	response, problem = serveProblem(engine, "GET", "/private")
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, "Unauthorized", problem.Title)
+
+	response, problem = serveProblem(engine, "GET", "/panic")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, grok.ProblemContentType, response.Header().Get("Content-Type"))
+	assert.Equal(t, "request-id", problem.Instance)
+}
+
+func TestLegacyErrors(t *testing.T) {
+	engine := problemEngine()
+
+	response, _ := serveProblem(engine, "GET", "/not-found")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.JSONEq(t, `{"code":404,"key":"CUSTOMER_NOT_FOUND","messages":["customer not found"]}`, response.Body.String())
+
+	response, _ = serveProblem(engine, "GET", "/unexpected")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Empty(t, response.Body.String())
+
+	response, _ = serveProblem(engine, "GET", "/private")
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Empty(t, response.Body.String())
+}
@@ -1,12 +1,16 @@
 package grok
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -15,28 +19,53 @@ import (
 
 // MessageBrokerProducer ...
 type MessageBrokerProducer struct {
-	snsSvc *sns.SNS
+	snsSvc  *sns.SNS
+	metrics *Metrics
+}
+
+// MessageBrokerProducerOption ...
+type MessageBrokerProducerOption func(*MessageBrokerProducer)
+
+// WithProducerMetrics - default DefaultMetrics
+func WithProducerMetrics(metrics *Metrics) MessageBrokerProducerOption {
+	return func(p *MessageBrokerProducer) {
+		p.metrics = metrics
+	}
 }
 
 // NewMessageBrokerProducer ...
-func NewMessageBrokerProducer(s *session.Session) *MessageBrokerProducer {
+func NewMessageBrokerProducer(s *session.Session, opts ...MessageBrokerProducerOption) *MessageBrokerProducer {
 	snsSvc := sns.New(s)
-	return &MessageBrokerProducer{snsSvc: snsSvc}
+	producer := &MessageBrokerProducer{snsSvc: snsSvc, metrics: DefaultMetrics}
+
+	for _, opt := range opts {
+		opt(producer)
+	}
+
+	return producer
 }
 
-// Publish ...
+// Publish publishes without a context, the message carries neither the
+// request id nor the trace of the caller. Use PublishWithContext.
 func (p *MessageBrokerProducer) Publish(topicID string, data interface{}, attributes map[string]string) (string, error) {
 	messageId, err := p.PublishWithAttributes(topicID, data, attributes)
 	return messageId, err
 }
 
-// PublishMany ...
+// PublishMany publishes without a context, the messages carry neither the
+// request id nor the trace of the caller. Use PublishManyWithContext.
 func (p *MessageBrokerProducer) PublishMany(topics []string, data interface{}) (map[string]string, map[string]error) {
+	return p.PublishManyWithContext(context.Background(), topics, data)
+}
+
+// PublishManyWithContext publishes data to each topic propagating the request
+// id and trace context of ctx
+func (p *MessageBrokerProducer) PublishManyWithContext(ctx context.Context, topics []string, data interface{}) (map[string]string, map[string]error) {
 	publishErrors := make(map[string]error, len(topics))
 	publishOk := make(map[string]string, len(topics))
 
 	for _, topicName := range topics {
-		messageId, err := p.Publish(topicName, data, nil)
+		messageId, err := p.PublishWithContext(ctx, topicName, data, nil)
 		if err != nil {
 			publishErrors[topicName] = err
 			logrus.WithError(err).
@@ -50,8 +79,38 @@ func (p *MessageBrokerProducer) PublishMany(topics []string, data interface{}) (
 	return publishOk, publishErrors
 }
 
-// PublishWihAttribrutes ...
+// PublishWithAttributes publishes without a context, the message carries
+// neither the request id nor the trace of the caller. Use PublishWithContext.
 func (p *MessageBrokerProducer) PublishWithAttributes(topicID string, data interface{}, attributes map[string]string) (string, error) {
+	return p.PublishWithContext(context.Background(), topicID, data, attributes)
+}
+
+// PublishWithContext publishes propagating the request id and trace context
+// of ctx as SNS message attributes
+func (p *MessageBrokerProducer) PublishWithContext(ctx context.Context, topicID string, data interface{}, attributes map[string]string) (string, error) {
+	ctx, span := tracer().Start(ctx, fmt.Sprintf("%s publish", topicID),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("sns"),
+			semconv.MessagingDestinationKey.String(topicID),
+		))
+	defer span.End()
+
+	messageID, err := p.publish(ctx, topicID, data, attributes)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if p.metrics != nil {
+		p.metrics.ObservePublish(topicID, err)
+	}
+
+	return messageID, err
+}
+
+func (p *MessageBrokerProducer) publish(ctx context.Context, topicID string, data interface{}, attributes map[string]string) (string, error) {
 	body, err := json.Marshal(data)
 
 	if err != nil {
@@ -98,7 +157,17 @@ func (p *MessageBrokerProducer) PublishWithAttributes(topicID string, data inter
 		}
 	}
 
-	output, err := p.snsSvc.Publish(snsPublishInput)
+	if snsPublishInput.MessageAttributes == nil {
+		snsPublishInput.MessageAttributes = make(map[string]*sns.MessageAttributeValue)
+	}
+
+	Propagator.Inject(ctx, snsAttributesCarrier(snsPublishInput.MessageAttributes))
+
+	if requestID := GetRequestID(ctx); requestID != "" {
+		snsAttributesCarrier(snsPublishInput.MessageAttributes).Set(RequestIDAttribute, requestID)
+	}
+
+	output, err := p.snsSvc.PublishWithContext(ctx, snsPublishInput)
 	if err != nil {
 		return "", err
 	}
@@ -1,6 +1,7 @@
 package grok_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/contbank/grok"
@@ -55,3 +56,16 @@ func (s *ProducerTestSuite) TestPublishFIFO() {
 	s.assert.NoError(err)
 	s.assert.NotNil(messageId)
 }
+
+func (s *ProducerTestSuite) TestPublishManyWithContext() {
+	session := grok.FakeSession(s.settings.AWS.SNS.Endpoint, s.settings.AWS.SNS.Region)
+	producer := grok.NewMessageBrokerProducer(session)
+
+	ctx := grok.ContextWithRequestID(context.Background(), "request-1")
+
+	published, errors := producer.PublishManyWithContext(ctx, []string{"test-topic", "test-topic-2"},
+		map[string]interface{}{"ping": "pong"})
+
+	s.assert.Empty(errors)
+	s.assert.Len(published, 2)
+}
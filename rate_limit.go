@@ -0,0 +1,403 @@
+package grok
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TokenBucket refills Limit tokens evenly over Period and allows bursts up
+	// to Limit requests
+	TokenBucket = "token_bucket"
+	// SlidingWindow allows at most Limit requests in any Period
+	SlidingWindow = "sliding_window"
+
+	// RateLimitByIP keys requests by client ip
+	RateLimitByIP = "ip"
+	// RateLimitBySubject keys requests by the sub claim
+	RateLimitBySubject = "sub"
+	// RateLimitByIdentity keys requests by the X-Current-Identity header
+	RateLimitByIdentity = "identity"
+	// RateLimitByRoute keys requests by the matched route
+	RateLimitByRoute = "route"
+
+	defaultRateLimitPrefix = "rate_limit"
+
+	memoryRateLimitSweepInterval = time.Second
+)
+
+// RateLimit describes the limit applied to a route group
+type RateLimit struct {
+	Algorithm string `yaml:"algorithm" validate:"omitempty,oneof=token_bucket sliding_window"` // token_bucket (default) or sliding_window
+	Limit     int64  `yaml:"limit" validate:"gte=1"`
+	Period    int64  `yaml:"period" validate:"gte=1"`                              // seconds
+	Key       string `yaml:"key" validate:"omitempty,oneof=ip sub identity route"` // ip (default), sub, identity or route
+
+	// KeyFunc overrides Key with a custom key extractor
+	KeyFunc func(*gin.Context) string `yaml:"-"`
+}
+
+// RateLimitResult ...
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the rate limit counters
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit *RateLimit) (*RateLimitResult, error)
+}
+
+// CreateRateLimitStore creates a redis store, or an in-memory one when fake
+func CreateRateLimitStore(settings *Settings) RateLimitStore {
+	prefix := defaultRateLimitPrefix
+
+	if settings.RateLimit != nil {
+		if settings.RateLimit.Fake {
+			return NewMemoryRateLimitStore()
+		}
+		if settings.RateLimit.Prefix != "" {
+			prefix = settings.RateLimit.Prefix
+		}
+	}
+
+	return NewRedisRateLimitStore(NewRedisConnection(settings.Redis.ConnectionString), prefix)
+}
+
+// RateLimitMiddleware rejects requests over the limit with 429 and sets the
+// RateLimit-* headers. Store failures do not block requests.
+func RateLimitMiddleware(store RateLimitStore, limit *RateLimit) gin.HandlerFunc {
+	keyFunc := limit.KeyFunc
+	if keyFunc == nil {
+		keyFunc = rateLimitKeyFunc(limit.Key)
+	}
+
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+
+		result, err := store.Allow(c.Request.Context(), key, limit)
+		if err != nil {
+			logrus.WithError(err).
+				WithField("key", key).
+				Error("error checking rate limit")
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			abortWithError(c, http.StatusTooManyRequests,
+				NewError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "rate limit exceeded"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// NamedRateLimitMiddleware rate limits with the limit name of the rate_limit
+// settings, keeping its counters apart from the other limits. It panics
+// when the limit is not configured.
+func NamedRateLimitMiddleware(store RateLimitStore, settings *Settings, name string) gin.HandlerFunc {
+	limit, err := namedRateLimit(settings, name)
+	if err != nil {
+		logrus.WithError(err).Panic("invalid rate limit")
+	}
+
+	return RateLimitMiddleware(store, limit)
+}
+
+func namedRateLimit(settings *Settings, name string) (*RateLimit, error) {
+	if settings.RateLimit == nil || settings.RateLimit.Limits[name] == nil {
+		return nil, fmt.Errorf("rate limit %s not configured", name)
+	}
+
+	limit := *settings.RateLimit.Limits[name]
+
+	keyFunc := limit.KeyFunc
+	if keyFunc == nil {
+		keyFunc = rateLimitKeyFunc(limit.Key)
+	}
+	limit.KeyFunc = func(c *gin.Context) string {
+		return name + ":" + keyFunc(c)
+	}
+
+	return &limit, nil
+}
+
+// rateLimitKeyFunc keys anonymous requests, without the sub claim or the
+// identity header, by client ip so they do not share a single counter
+func rateLimitKeyFunc(kind string) func(*gin.Context) string {
+	switch kind {
+	case RateLimitBySubject:
+		return func(c *gin.Context) string {
+			if sub := c.GetString("sub"); sub != "" {
+				return "sub:" + sub
+			}
+			return "ip:" + c.ClientIP()
+		}
+	case RateLimitByIdentity:
+		return func(c *gin.Context) string {
+			if identity := c.GetHeader(CurrentIdentityHeader); identity != "" {
+				return "identity:" + identity
+			}
+			return "ip:" + c.ClientIP()
+		}
+	case RateLimitByRoute:
+		return func(c *gin.Context) string {
+			return "route:" + c.Request.Method + " " + c.FullPath()
+		}
+	default:
+		return func(c *gin.Context) string {
+			return "ip:" + c.ClientIP()
+		}
+	}
+}
+
+func (l *RateLimit) period() time.Duration {
+	return time.Duration(l.Period) * time.Second
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// RedisRateLimitStore shares the counters between instances
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimitStore ...
+func NewRedisRateLimitStore(client *redis.Client, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+local rate = capacity / period
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, math.floor(tokens), math.ceil((capacity - tokens) / rate), retry}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], 0, now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+local reset = window
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+return {allowed, limit - count, reset, retry}
+`)
+
+// Allow ...
+func (s *RedisRateLimitStore) Allow(ctx context.Context, key string, limit *RateLimit) (*RateLimitResult, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	period := limit.period().Milliseconds()
+	keys := []string{fmt.Sprintf("%s:%s", s.prefix, key)}
+
+	var values []interface{}
+	var err error
+
+	if limit.Algorithm == SlidingWindow {
+		member := fmt.Sprintf("%d-%d", now, rand.Int63())
+		values, err = slidingWindowScript.Run(ctx, s.client, keys, limit.Limit, period, now, member).Slice()
+	} else {
+		values, err = tokenBucketScript.Run(ctx, s.client, keys, limit.Limit, period, now).Slice()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	result := &RateLimitResult{Limit: limit.Limit}
+	fields := make([]int64, len(values))
+	for i, value := range values {
+		n, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+		}
+		fields[i] = n
+	}
+
+	result.Allowed = fields[0] == 1
+	result.Remaining = fields[1]
+	result.Reset = time.Duration(fields[2]) * time.Millisecond
+	result.RetryAfter = time.Duration(fields[3]) * time.Millisecond
+
+	return result, nil
+}
+
+// MemoryRateLimitStore keeps the counters in process. It is meant for tests
+// and single instance deployments. Keys idle for their period are back to
+// their initial state and are dropped.
+type MemoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	windows map[string][]time.Time
+	idle    map[string]time.Time
+	swept   time.Time
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// NewMemoryRateLimitStore ...
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*tokenBucket{},
+		windows: map[string][]time.Time{},
+		idle:    map[string]time.Time{},
+		now:     time.Now,
+	}
+}
+
+// Allow ...
+func (s *MemoryRateLimitStore) Allow(ctx context.Context, key string, limit *RateLimit) (*RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.evict(now)
+	s.idle[key] = now.Add(limit.period())
+
+	if limit.Algorithm == SlidingWindow {
+		return s.slidingWindow(key, limit, now), nil
+	}
+
+	return s.tokenBucket(key, limit, now), nil
+}
+
+// evict drops the idle keys, at most once per sweep interval
+func (s *MemoryRateLimitStore) evict(now time.Time) {
+	if now.Sub(s.swept) < memoryRateLimitSweepInterval {
+		return
+	}
+	s.swept = now
+
+	for key, idle := range s.idle {
+		if !idle.After(now) {
+			delete(s.idle, key)
+			delete(s.buckets, key)
+			delete(s.windows, key)
+		}
+	}
+}
+
+func (s *MemoryRateLimitStore) tokenBucket(key string, limit *RateLimit, now time.Time) *RateLimitResult {
+	capacity := float64(limit.Limit)
+	rate := capacity / float64(limit.period())
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, ts: now}
+		s.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.ts); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)*rate)
+	}
+	bucket.ts = now
+
+	result := &RateLimitResult{Limit: limit.Limit}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - bucket.tokens) / rate))
+	}
+
+	result.Remaining = int64(bucket.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - bucket.tokens) / rate))
+
+	return result
+}
+
+func (s *MemoryRateLimitStore) slidingWindow(key string, limit *RateLimit, now time.Time) *RateLimitResult {
+	window := limit.period()
+
+	hits := s.windows[key][:0]
+	for _, hit := range s.windows[key] {
+		if now.Sub(hit) < window {
+			hits = append(hits, hit)
+		}
+	}
+
+	result := &RateLimitResult{Limit: limit.Limit}
+
+	if int64(len(hits)) < limit.Limit {
+		hits = append(hits, now)
+		result.Allowed = true
+	}
+
+	s.windows[key] = hits
+
+	result.Remaining = limit.Limit - int64(len(hits))
+	result.Reset = window
+	if len(hits) > 0 {
+		result.Reset = hits[0].Add(window).Sub(now)
+	}
+	if !result.Allowed {
+		result.RetryAfter = result.Reset
+	}
+
+	return result
+}
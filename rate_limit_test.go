@@ -0,0 +1,160 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitedEngine(store grok.RateLimitStore, limit *grok.RateLimit) *gin.Engine {
+	engine := gin.New()
+	group := engine.Group("/limited", grok.RateLimitMiddleware(store, limit))
+	group.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/free", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return engine
+}
+
+func rateLimitRequest(engine *gin.Engine, path string, identity string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(grok.CurrentIdentityHeader, identity)
+	engine.ServeHTTP(response, req)
+	return response
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	for _, algorithm := range []string{grok.TokenBucket, grok.SlidingWindow} {
+		engine := rateLimitedEngine(grok.NewMemoryRateLimitStore(), &grok.RateLimit{
+			Algorithm: algorithm,
+			Limit:     2,
+			Period:    60,
+		})
+
+		response := rateLimitRequest(engine, "/limited", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", response.Header().Get("RateLimit-Remaining"))
+
+		assert.Equal(t, http.StatusOK, rateLimitRequest(engine, "/limited", "").Code)
+
+		response = rateLimitRequest(engine, "/limited", "")
+		assert.Equal(t, http.StatusTooManyRequests, response.Code, algorithm)
+		assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, response.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"code":429,"key":"TOO_MANY_REQUESTS","messages":["rate limit exceeded"]}`, response.Body.String())
+
+		assert.Equal(t, http.StatusOK, rateLimitRequest(engine, "/free", "").Code)
+	}
+}
+
+func TestRateLimitByIdentity(t *testing.T) {
+	engine := rateLimitedEngine(grok.CreateRateLimitStore(&grok.Settings{
+		RateLimit: &grok.RateLimitSettings{Fake: true},
+	}), &grok.RateLimit{
+		Limit:  1,
+		Period: 60,
+		Key:    grok.RateLimitByIdentity,
+	})
+
+	assert.Equal(t, http.StatusOK, rateLimitRequest(engine, "/limited", "12345678909").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitRequest(engine, "/limited", "12345678909").Code)
+	assert.Equal(t, http.StatusOK, rateLimitRequest(engine, "/limited", "00000000000").Code)
+}
+
+func TestNamedRateLimitMiddleware(t *testing.T) {
+	settings := &grok.Settings{
+		RateLimit: &grok.RateLimitSettings{
+			Fake: true,
+			Limits: map[string]*grok.RateLimit{
+				"transfers": {Limit: 1, Period: 60},
+				"accounts":  {Limit: 2, Period: 60},
+			},
+		},
+	}
+	store := grok.CreateRateLimitStore(settings)
+
+	engine := gin.New()
+	engine.GET("/transfers", grok.NamedRateLimitMiddleware(store, settings, "transfers"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/accounts", grok.NamedRateLimitMiddleware(store, settings, "accounts"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, http.StatusOK, rateLimitRequest(engine, "/transfers", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitRequest(engine, "/transfers", "").Code)
+
+	response := rateLimitRequest(engine, "/accounts", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+
+	assert.Panics(t, func() {
+		grok.NamedRateLimitMiddleware(store, settings, "unknown")
+	})
+}
+
+func TestRateLimitAnonymousSubject(t *testing.T) {
+	engine := rateLimitedEngine(grok.NewMemoryRateLimitStore(), &grok.RateLimit{
+		Limit:  1,
+		Period: 60,
+		Key:    grok.RateLimitBySubject,
+	})
+
+	request := func(remoteAddr string) int {
+		response := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/limited", nil)
+		req.RemoteAddr = remoteAddr
+		engine.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1234"))
+}
+
+func TestRateLimitSettingsValidation(t *testing.T) {
+	err := grok.ValidateSettings(&grok.Settings{
+		RateLimit: &grok.RateLimitSettings{
+			Limits: map[string]*grok.RateLimit{"transfers": {Limit: 10}},
+		},
+	})
+
+	assert.ErrorContains(t, err, "rate_limit.limits[transfers].period")
+
+	err = grok.ValidateSettings(&grok.Settings{
+		RateLimit: &grok.RateLimitSettings{
+			Limits: map[string]*grok.RateLimit{"transfers": {Algorithm: "sliding-window", Limit: 10, Period: 60, Key: "subject"}},
+		},
+	})
+
+	assert.ErrorContains(t, err, "rate_limit.limits[transfers].algorithm")
+	assert.ErrorContains(t, err, "rate_limit.limits[transfers].key")
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	store := grok.NewMemoryRateLimitStore()
+	now := time.Now()
+	store.SetNow(func() time.Time { return now })
+
+	ctx := context.Background()
+	limit := &grok.RateLimit{Limit: 1, Period: 60}
+
+	for _, key := range []string{"ip:10.0.0.1", "ip:10.0.0.2"} {
+		result, err := store.Allow(ctx, key, limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, _ := store.Allow(ctx, "ip:10.0.0.1", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 4, store.Keys())
+
+	now = now.Add(61 * time.Second)
+
+	result, _ = store.Allow(ctx, "ip:10.0.0.3", &grok.RateLimit{Algorithm: grok.SlidingWindow, Limit: 1, Period: 60})
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, store.Keys())
+}
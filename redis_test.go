@@ -1,7 +1,9 @@
 package grok_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/contbank/grok"
 	"github.com/stretchr/testify/assert"
@@ -35,3 +37,43 @@ func (s *RedisTestSuite) TestConnectFail() {
 		grok.NewRedisConnection("nohost")
 	})
 }
+
+func (s *RedisTestSuite) TestRateLimitStore() {
+	store := grok.NewRedisRateLimitStore(grok.NewRedisConnection(s.settings.Redis.ConnectionString), "grok_test")
+
+	for _, algorithm := range []string{grok.TokenBucket, grok.SlidingWindow} {
+		limit := &grok.RateLimit{Algorithm: algorithm, Limit: 1, Period: 60}
+		key := grok.GeneratorIDBase(10)
+
+		result, err := store.Allow(context.Background(), key, limit)
+		s.assert.NoError(err)
+		s.assert.True(result.Allowed)
+
+		result, err = store.Allow(context.Background(), key, limit)
+		s.assert.NoError(err)
+		s.assert.False(result.Allowed)
+		s.assert.True(result.RetryAfter > 0)
+	}
+}
+
+func (s *RedisTestSuite) TestRevocationStore() {
+	store := grok.NewRedisRevocationStore(grok.NewRedisConnection(s.settings.Redis.ConnectionString), "grok_test", time.Hour)
+	ctx := context.Background()
+	jti := grok.GeneratorIDBase(10)
+	sub := grok.GeneratorIDBase(10)
+
+	s.assert.NoError(store.RevokeToken(ctx, jti, time.Now().Add(time.Minute)))
+	s.assert.NoError(store.RevokeSubject(ctx, sub, time.Now()))
+
+	revoked, err := store.IsRevoked(ctx, map[string]interface{}{"jti": jti})
+	s.assert.NoError(err)
+	s.assert.True(revoked)
+
+	revoked, err = store.IsRevoked(ctx, map[string]interface{}{"sub": sub, "iat": float64(time.Now().Add(-time.Minute).Unix())})
+	s.assert.NoError(err)
+	s.assert.True(revoked)
+
+	revoked, err = store.IsRevoked(ctx, map[string]interface{}{"sub": sub, "iat": float64(time.Now().Add(time.Minute).Unix())})
+	s.assert.NoError(err)
+	s.assert.False(revoked)
+}
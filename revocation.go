@@ -0,0 +1,206 @@
+package grok
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultRevocationPrefix     = "revoked_tokens"
+	defaultRevocationSubjectTTL = 24 * time.Hour
+)
+
+var (
+	// ErrTokenRevoked ...
+	ErrTokenRevoked = NewError(http.StatusUnauthorized, "TOKEN_REVOKED", "token revoked")
+)
+
+// RevocationSettings ...
+type RevocationSettings struct {
+	Fake       bool   `yaml:"fake"`
+	Prefix     string `yaml:"prefix"`                       // redis key prefix, default revoked_tokens
+	SubjectTTL int64  `yaml:"subject_ttl" validate:"gte=0"` // seconds, default 24h, the longest token lifetime
+}
+
+// RevocationStore keeps the revoked tokens, by jti, and the subjects whose
+// tokens issued before a given time are revoked, e.g. on logout
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeSubject(ctx context.Context, sub string, issuedBefore time.Time) error
+	IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error)
+}
+
+// CreateRevocationStore creates a redis store, or an in-memory one when fake
+func CreateRevocationStore(settings *Settings) RevocationStore {
+	prefix := defaultRevocationPrefix
+	subjectTTL := defaultRevocationSubjectTTL
+
+	if settings.Revocation != nil {
+		if settings.Revocation.Fake {
+			return NewMemoryRevocationStore()
+		}
+		if settings.Revocation.Prefix != "" {
+			prefix = settings.Revocation.Prefix
+		}
+		if settings.Revocation.SubjectTTL > 0 {
+			subjectTTL = time.Duration(settings.Revocation.SubjectTTL) * time.Second
+		}
+	}
+
+	return NewRedisRevocationStore(NewRedisConnection(settings.Redis.ConnectionString), prefix, subjectTTL)
+}
+
+// RedisRevocationStore ...
+type RedisRevocationStore struct {
+	client     *redis.Client
+	prefix     string
+	subjectTTL time.Duration
+}
+
+// NewRedisRevocationStore ...
+func NewRedisRevocationStore(client *redis.Client, prefix string, subjectTTL time.Duration) *RedisRevocationStore {
+	return &RedisRevocationStore{client: client, prefix: prefix, subjectTTL: subjectTTL}
+}
+
+// RevokeToken keeps jti revoked until the token expires
+func (s *RedisRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.key("jti", jti), 1, ttl).Err()
+}
+
+// RevokeSubject revokes the tokens of sub issued up to issuedBefore
+func (s *RedisRevocationStore) RevokeSubject(ctx context.Context, sub string, issuedBefore time.Time) error {
+	return s.client.Set(ctx, s.key("sub", sub), issuedBefore.Unix(), s.subjectTTL).Err()
+}
+
+// IsRevoked ...
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+
+	if jti == "" && sub == "" {
+		return false, nil
+	}
+
+	values, err := s.client.MGet(ctx, s.key("jti", jti), s.key("sub", sub)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if jti != "" && values[0] != nil {
+		return true, nil
+	}
+
+	if sub == "" || values[1] == nil {
+		return false, nil
+	}
+
+	issuedBefore, err := strconv.ParseInt(fmt.Sprint(values[1]), 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	return issuedUpTo(claims, issuedBefore), nil
+}
+
+func (s *RedisRevocationStore) key(kind string, value string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, kind, value)
+}
+
+// MemoryRevocationStore keeps the revocations in process. It is meant for
+// tests and single instance deployments.
+type MemoryRevocationStore struct {
+	mutex    sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[string]int64
+}
+
+// NewMemoryRevocationStore ...
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   map[string]time.Time{},
+		subjects: map[string]int64{},
+	}
+}
+
+// RevokeToken ...
+func (s *MemoryRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, expiry := range s.tokens {
+		if !expiry.After(now) {
+			delete(s.tokens, key)
+		}
+	}
+
+	if expiresAt.After(now) {
+		s.tokens[jti] = expiresAt
+	}
+
+	return nil
+}
+
+// RevokeSubject ...
+func (s *MemoryRevocationStore) RevokeSubject(ctx context.Context, sub string, issuedBefore time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.subjects[sub] = issuedBefore.Unix()
+
+	return nil
+}
+
+// IsRevoked ...
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if jti, ok := claims["jti"].(string); ok {
+		if expiry, found := s.tokens[jti]; found && expiry.After(time.Now()) {
+			return true, nil
+		}
+	}
+
+	if sub, ok := claims["sub"].(string); ok {
+		if issuedBefore, found := s.subjects[sub]; found {
+			return issuedUpTo(claims, issuedBefore), nil
+		}
+	}
+
+	return false, nil
+}
+
+// issuedUpTo reports whether the token was issued at or before
+// issuedBefore, tokens without iat are considered revoked
+func issuedUpTo(claims map[string]interface{}, issuedBefore int64) bool {
+	iat, ok := numericClaim(claims, "iat")
+	if !ok {
+		return true
+	}
+	return iat <= issuedBefore
+}
+
+// numericClaim reads a NumericDate claim such as exp or iat
+func numericClaim(claims map[string]interface{}, key string) (int64, bool) {
+	switch value := claims[key].(type) {
+	case float64:
+		return int64(value), true
+	case int64:
+		return value, true
+	case int:
+		return int64(value), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,115 @@
+package grok_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/contbank/grok/groktest"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingRevocationStore struct {
+	grok.RevocationStore
+}
+
+func (s *failingRevocationStore) IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := grok.NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeToken(ctx, "revoked", now.Add(time.Hour)))
+	assert.NoError(t, store.RevokeToken(ctx, "expired", now.Add(-time.Hour)))
+	assert.NoError(t, store.RevokeSubject(ctx, "auth0|123", now))
+
+	revoked, err := store.IsRevoked(ctx, map[string]interface{}{"jti": "revoked", "sub": "auth0|456"})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, map[string]interface{}{"jti": "expired", "sub": "auth0|456"})
+	assert.False(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, map[string]interface{}{"sub": "auth0|123", "iat": float64(now.Add(-time.Minute).Unix())})
+	assert.True(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, map[string]interface{}{"sub": "auth0|123", "iat": float64(now.Add(time.Minute).Unix())})
+	assert.False(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, map[string]interface{}{"sub": "auth0|123"})
+	assert.True(t, revoked)
+}
+
+func TestAuthenticateRevocation(t *testing.T) {
+	issuer := newLocalIssuer(t)
+	store := grok.CreateRevocationStore(&grok.Settings{Revocation: &grok.RevocationSettings{Fake: true}})
+
+	authenticate := grok.NewAuthenticate(issuer.APIAuth(), cache.New(time.Minute, time.Minute),
+		grok.WithRevocationStore(store))
+
+	engine := gin.New()
+	engine.GET("/", authenticate.Middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	ctx := context.Background()
+
+	token := authorization(t, issuer, groktest.WithClaim("jti", "token-1"))
+	assert.Equal(t, http.StatusOK, request(token))
+
+	assert.NoError(t, store.RevokeToken(ctx, "token-1", time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, request(token))
+
+	session := authorization(t, issuer, groktest.WithSubject("auth0|123"))
+	assert.Equal(t, http.StatusOK, request(session))
+
+	assert.NoError(t, store.RevokeSubject(ctx, "auth0|123", time.Now()))
+	assert.Equal(t, http.StatusUnauthorized, request(session))
+
+	login := authorization(t, issuer, groktest.WithSubject("auth0|123"),
+		groktest.WithClaim("iat", time.Now().Add(time.Second).Unix()))
+	assert.Equal(t, http.StatusOK, request(login))
+
+	failing := grok.NewAuthenticate(issuer.APIAuth(), cache.New(time.Minute, time.Minute),
+		grok.WithRevocationStore(&failingRevocationStore{})).(grok.TokenValidator)
+	_, err := failing.ValidateToken(ctx, authorization(t, issuer))
+	assert.Error(t, err)
+}
+
+func TestAuthenticateCacheExpiry(t *testing.T) {
+	issuer := newLocalIssuer(t)
+	claims := cache.New(time.Minute, time.Minute)
+	authenticate := grok.NewAuthenticate(issuer.APIAuth(), claims).(grok.TokenValidator)
+
+	token := authorization(t, issuer, groktest.WithExpiry(10*time.Minute))
+
+	_, err := authenticate.ValidateToken(context.Background(), token)
+	assert.NoError(t, err)
+
+	items := claims.Items()
+	assert.Len(t, items, 1)
+
+	for key, item := range items {
+		assert.NotContains(t, token, key)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), time.Unix(0, item.Expiration), 5*time.Second)
+	}
+
+	_, err = authenticate.ValidateToken(context.Background(), authorization(t, issuer, groktest.WithExpiry(-2*time.Minute)))
+	assert.Error(t, err)
+	assert.Len(t, claims.Items(), 1)
+}
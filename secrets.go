@@ -0,0 +1,346 @@
+package grok
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// SecretScheme prefixes settings values resolved from the secret store,
+	// e.g. secret://prod/sendgrid#api_key
+	SecretScheme = "secret://"
+
+	// SecretsManagerBackend reads secrets from AWS Secrets Manager
+	SecretsManagerBackend = "secrets_manager"
+	// FileSecretBackend reads secrets from files, meant for development and
+	// tests
+	FileSecretBackend = "file"
+
+	defaultSecretsCacheTTL = 5 * time.Minute
+)
+
+// SecretsSettings ...
+type SecretsSettings struct {
+	Backend  string `yaml:"backend" validate:"omitempty,oneof=secrets_manager file"` // secrets_manager (default) or file
+	Path     string `yaml:"path" validate:"required_if=Backend file"`                // directory of the file backend
+	CacheTTL int64  `yaml:"cache_ttl" validate:"gte=0"`                              // seconds, default 300
+}
+
+// SecretStore ...
+type SecretStore interface {
+	GetSecret(name string) (string, error)
+}
+
+// CreateSecretStore creates a cached secrets manager store using the aws
+// secrets_manager credentials, or a file store with the file backend
+func CreateSecretStore(settings *Settings) (SecretStore, error) {
+	ttl := defaultSecretsCacheTTL
+	backend := SecretsManagerBackend
+
+	if settings.Secrets != nil {
+		if settings.Secrets.CacheTTL > 0 {
+			ttl = time.Duration(settings.Secrets.CacheTTL) * time.Second
+		}
+		if settings.Secrets.Backend != "" {
+			backend = settings.Secrets.Backend
+		}
+	}
+
+	switch backend {
+	case FileSecretBackend:
+		return NewCachedSecretStore(NewFileSecretStore(settings.Secrets.Path), ttl), nil
+	case SecretsManagerBackend:
+		if settings.AWS == nil || settings.AWS.SecretsManager == nil {
+			return nil, fmt.Errorf("aws.secrets_manager is required to resolve secrets")
+		}
+		session := CreateSession(&settings.AWS.SecretsManager.Aws)
+		return NewCachedSecretStore(NewSecretsManager(session), ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %s", backend)
+	}
+}
+
+// FileSecretStore reads the secret name from the file of the same name in
+// its directory, e.g. prod/sendgrid from <path>/prod/sendgrid
+type FileSecretStore struct {
+	path string
+}
+
+// NewFileSecretStore ...
+func NewFileSecretStore(path string) *FileSecretStore {
+	return &FileSecretStore{path: path}
+}
+
+// GetSecret ...
+func (s *FileSecretStore) GetSecret(name string) (string, error) {
+	file := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(file) || file == ".." || strings.HasPrefix(file, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid secret name %s", name)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.path, file))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// CachedSecretStore keeps the secrets read from store for ttl
+type CachedSecretStore struct {
+	store SecretStore
+	cache *cache.Cache
+}
+
+// NewCachedSecretStore ...
+func NewCachedSecretStore(store SecretStore, ttl time.Duration) *CachedSecretStore {
+	return &CachedSecretStore{
+		store: store,
+		cache: cache.New(ttl, ttl),
+	}
+}
+
+// GetSecret ...
+func (s *CachedSecretStore) GetSecret(name string) (string, error) {
+	if value, found := s.cache.Get(name); found {
+		return value.(string), nil
+	}
+
+	value, err := s.store.GetSecret(name)
+	if err != nil {
+		return "", err
+	}
+
+	s.cache.SetDefault(name, value)
+
+	return value, nil
+}
+
+// ResolveSecret resolves a secret://name#key reference. Without a key the
+// whole secret is returned, otherwise the secret is read as a JSON object and
+// the value of key is returned.
+func ResolveSecret(store SecretStore, reference string) (string, error) {
+	name := strings.TrimPrefix(reference, SecretScheme)
+	key := ""
+
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		name, key = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("secret name is required")
+	}
+
+	secret, err := store.GetSecret(name)
+	if err != nil {
+		return "", err
+	}
+
+	if key == "" {
+		return secret, nil
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %v", name, err)
+	}
+
+	value, found := values[key]
+	if !found {
+		return "", fmt.Errorf("key %s not found in secret %s", key, name)
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// ResolveSecrets replaces the secret:// references in the string values of
+// dist, a pointer to a settings struct
+func ResolveSecrets(store SecretStore, dist interface{}) error {
+	resolver := &secretResolver{store: func() (SecretStore, error) { return store, nil }}
+	return resolver.resolve("", reflect.ValueOf(dist))
+}
+
+// resolveSettingsSecrets resolves the secret references of dist with the
+// store configured in its grok settings. The store is only created when a
+// reference is found.
+func resolveSettingsSecrets(dist interface{}) error {
+	var store SecretStore
+
+	resolver := &secretResolver{store: func() (SecretStore, error) {
+		if store != nil {
+			return store, nil
+		}
+
+		settings := findSettings(reflect.ValueOf(dist))
+		if settings == nil {
+			return nil, fmt.Errorf("grok settings not found")
+		}
+
+		var err error
+		store, err = CreateSecretStore(settings)
+		return store, err
+	}}
+
+	return resolver.resolve("", reflect.ValueOf(dist))
+}
+
+type secretResolver struct {
+	store func() (SecretStore, error)
+}
+
+func (r *secretResolver) resolve(path string, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return r.resolve(path, value.Elem())
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Elem().Kind() == reflect.String {
+			resolved, changed, err := r.resolveString(path, value.Elem().String())
+			if err != nil || !changed {
+				return err
+			}
+			if value.CanSet() {
+				value.Set(reflect.ValueOf(resolved))
+			}
+			return nil
+		}
+		return r.resolve(path, value.Elem())
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+				continue
+			}
+			if err := r.resolve(joinPath(path, yamlKey(field)), value.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := r.resolve(fmt.Sprintf("%s[%d]", path, i), value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			item := value.MapIndex(key)
+			itemPath := joinPath(path, fmt.Sprint(key.Interface()))
+
+			if item.Kind() == reflect.Interface && !item.IsNil() {
+				item = item.Elem()
+			}
+
+			if item.Kind() != reflect.String {
+				if err := r.resolve(itemPath, item); err != nil {
+					return err
+				}
+				continue
+			}
+
+			resolved, changed, err := r.resolveString(itemPath, item.String())
+			if err != nil {
+				return err
+			}
+			if changed {
+				value.SetMapIndex(key, reflect.ValueOf(resolved).Convert(value.Type().Elem()))
+			}
+		}
+	case reflect.String:
+		resolved, changed, err := r.resolveString(path, value.String())
+		if err != nil || !changed {
+			return err
+		}
+		if value.CanSet() {
+			value.SetString(resolved)
+		}
+	}
+
+	return nil
+}
+
+func (r *secretResolver) resolveString(path string, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, SecretScheme) {
+		return value, false, nil
+	}
+
+	store, err := r.store()
+	if err != nil {
+		return "", false, fmt.Errorf("error resolving %s at %s: %v", value, path, err)
+	}
+
+	resolved, err := ResolveSecret(store, value)
+	if err != nil {
+		return "", false, fmt.Errorf("error resolving %s at %s: %v", value, path, err)
+	}
+
+	return resolved, true, nil
+}
+
+func joinPath(path string, key string) string {
+	switch {
+	case key == "":
+		return path
+	case path == "":
+		return key
+	default:
+		return path + "." + key
+	}
+}
+
+// findSettings returns dist when it is the grok settings or the first grok
+// settings field of dist
+func findSettings(value reflect.Value) *Settings {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		if settings, ok := value.Interface().(*Settings); ok {
+			return settings
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if value.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if field.Kind() == reflect.Struct && field.CanAddr() {
+			field = field.Addr()
+		}
+		if settings := findSettings(field); settings != nil {
+			return settings
+		}
+	}
+
+	return nil
+}
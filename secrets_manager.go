@@ -1,6 +1,8 @@
 package grok
 
 import (
+	"fmt"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
@@ -31,3 +33,21 @@ func (secretsManager *SecretsManager) LoadSecretsManager(key string) (string, er
 
 	return *result.SecretString, nil
 }
+
+// GetSecret implements SecretStore
+func (secretsManager *SecretsManager) GetSecret(name string) (string, error) {
+	svc := secretsmanager.New(secretsManager.session)
+
+	result, err := svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if result.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", name)
+	}
+
+	return *result.SecretString, nil
+}
@@ -0,0 +1,114 @@
+package grok
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSecurityHeaders are suited for JSON APIs that are never rendered by
+// a browser
+var DefaultSecurityHeaders = map[string]string{
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "DENY",
+	"Referrer-Policy":           "no-referrer",
+	"Cache-Control":             "no-store",
+}
+
+// SwaggerSecurityHeaders relax DefaultSecurityHeaders so the swagger UI can
+// load its scripts, styles and images
+var SwaggerSecurityHeaders = map[string]string{
+	"Content-Security-Policy": "default-src 'self'; script-src 'self' 'unsafe-inline'; " +
+		"style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'self'",
+	"X-Frame-Options": "SAMEORIGIN",
+	"Cache-Control":   "",
+}
+
+// SecurityHeadersMiddleware sets DefaultSecurityHeaders overridden by the
+// settings headers and then by the longest matching route prefix in routes
+// and in the settings routes. An empty value removes the header.
+func SecurityHeadersMiddleware(settings *SecurityHeadersSettings, routes map[string]map[string]string) gin.HandlerFunc {
+	base := mergeHeaders(DefaultSecurityHeaders)
+
+	overrides := map[string]map[string]string{}
+	for prefix, headers := range routes {
+		overrides[prefix] = headers
+	}
+
+	if settings != nil {
+		base = mergeHeaders(base, settings.Headers)
+		for prefix, headers := range settings.Routes {
+			overrides[prefix] = mergeHeaders(overrides[prefix], headers)
+		}
+	}
+
+	prefixes := make([]string, 0, len(overrides))
+	for prefix := range overrides {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	resolved := map[string]map[string]string{}
+	for _, prefix := range prefixes {
+		resolved[prefix] = mergeHeaders(base, overrides[prefix])
+	}
+
+	return func(c *gin.Context) {
+		headers := base
+
+		for _, prefix := range prefixes {
+			if matchesPathPrefix(c.Request.URL.Path, prefix) {
+				headers = resolved[prefix]
+				break
+			}
+		}
+
+		writeSecurityHeaders(c, headers)
+		c.Next()
+	}
+}
+
+// matchesPathPrefix matches whole path segments, /docs matches /docs and
+// /docs/index.html but not /docsearch
+func matchesPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// SecurityHeaders overrides the security headers of a route or group. An
+// empty value removes the header.
+func SecurityHeaders(headers map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeSecurityHeaders(c, headers)
+		c.Next()
+	}
+}
+
+func writeSecurityHeaders(c *gin.Context, headers map[string]string) {
+	header := c.Writer.Header()
+	for name, value := range headers {
+		if value == "" {
+			header.Del(name)
+			continue
+		}
+		header.Set(name, value)
+	}
+}
+
+// mergeHeaders returns a new map with canonical names where later headers
+// take precedence. Empty values are kept so they remove the header when
+// written.
+func mergeHeaders(headers ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, h := range headers {
+		for name, value := range h {
+			result[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package grok_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			SecurityHeaders: &grok.SecurityHeadersSettings{
+				Headers: map[string]string{
+					"referrer-policy": "same-origin",
+					"Cache-Control":   "",
+				},
+				Routes: map[string]map[string]string{
+					"/public": {"X-Frame-Options": "SAMEORIGIN"},
+				},
+			},
+		},
+	}
+
+	server := grok.New(
+		grok.WithSettings(settings),
+		grok.WithSecurityHeaders(),
+		grok.WithContainer(&testContainer{}))
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	server.Engine.GET("/customers", ok)
+	server.Engine.GET("/public/terms", ok)
+	server.Engine.GET("/publicity", ok)
+	server.Engine.GET("/embed", grok.SecurityHeaders(map[string]string{"X-Frame-Options": ""}), ok)
+
+	serve := func(path string) http.Header {
+		response := httptest.NewRecorder()
+		server.Engine.ServeHTTP(response, httptest.NewRequest("GET", path, nil))
+		return response.Header()
+	}
+
+	header := serve("/customers")
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", header.Get("X-Frame-Options"))
+	assert.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", header.Get("Content-Security-Policy"))
+	assert.Equal(t, "same-origin", header.Get("Referrer-Policy"))
+	assert.Empty(t, header.Get("Cache-Control"))
+
+	header = serve("/public/terms")
+	assert.Equal(t, "SAMEORIGIN", header.Get("X-Frame-Options"))
+	assert.Equal(t, "same-origin", header.Get("Referrer-Policy"))
+
+	header = serve("/publicity")
+	assert.Equal(t, "DENY", header.Get("X-Frame-Options"))
+
+	header = serve("/swagger")
+	assert.Contains(t, header.Get("Content-Security-Policy"), "script-src 'self' 'unsafe-inline'")
+
+	header = serve("/swagger/index.html")
+	assert.Contains(t, header.Get("Content-Security-Policy"), "script-src 'self' 'unsafe-inline'")
+
+	header = serve("/embed")
+	assert.Empty(t, header.Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"google.golang.org/grpc/reflection"
@@ -14,25 +16,42 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 	"github.com/swaggo/swag"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // API wraps API configurations.
 type API struct {
-	Engine *gin.Engine
-	router *gin.RouterGroup
+	Engine      *gin.Engine
+	AdminEngine *gin.Engine
+	router      *gin.RouterGroup
 
 	cors     bool
+	security bool
+	problems bool
+	metrics  *Metrics
 	settings *Settings
+	watcher  *SettingsWatcher
 	healthz  gin.HandlerFunc
 	handlers []gin.HandlerFunc
 
 	swagger    *SwaggerSettings
 	grpcServer *grpc.Server
 	Container  Container
+
+	grpcHealthz  *Healthz
+	healthServer *health.Server
+
+	tracing        bool
+	tracerProvider *sdktrace.TracerProvider
+
+	ready int32
 }
 
 // APIOption wrapps all server configurations
@@ -40,6 +59,7 @@ type APIOption func(server *API)
 
 func init() {
 	gin.SetMode("release")
+	binding.Validator = NewBindingValidator()
 }
 
 // WithContainer adds a container to the server
@@ -56,13 +76,55 @@ func WithSettings(settings *Settings) APIOption {
 	}
 }
 
-// WithCORS enables CORS
+// WithSettingsWatcher sets the server configurations from the watcher and
+// reloads the log middleware, the log level and the CORS origins with it
+func WithSettingsWatcher(watcher *SettingsWatcher) APIOption {
+	return func(server *API) {
+		server.watcher = watcher
+		server.settings = watcher.Settings()
+	}
+}
+
+// WithCORS enables CORS with the API cors settings. Without them every
+// cross-origin request is denied.
 func WithCORS() APIOption {
 	return func(server *API) {
 		server.cors = true
 	}
 }
 
+// WithProblemJSON renders grok errors as RFC 7807 problem documents instead
+// of the legacy {code,key,messages} format
+func WithProblemJSON() APIOption {
+	return func(server *API) {
+		server.problems = true
+	}
+}
+
+// WithSecurityHeaders sets DefaultSecurityHeaders, relaxed for the swagger
+// routes and overridden by the API security headers settings
+func WithSecurityHeaders() APIOption {
+	return func(server *API) {
+		server.security = true
+	}
+}
+
+// WithMetrics exposes DefaultMetrics at /metrics and instruments requests
+func WithMetrics() APIOption {
+	return func(server *API) {
+		server.metrics = DefaultMetrics
+	}
+}
+
+// WithTracing enables W3C trace context propagation. When the tracing
+// settings are present a tracer provider is created from them, otherwise the
+// global provider is used.
+func WithTracing() APIOption {
+	return func(server *API) {
+		server.tracing = true
+	}
+}
+
 // WithBaseHandler add a base handler
 func WithBaseHandler(h gin.HandlerFunc) APIOption {
 	return func(server *API) {
@@ -86,6 +148,29 @@ func WithSwagger(spec *swag.Spec, path string) APIOption {
 	}
 }
 
+// WithGRPCHealthz computes the grpc.health.v1 status from the given checks
+func WithGRPCHealthz(h *Healthz) APIOption {
+	return func(server *API) {
+		server.grpcHealthz = h
+	}
+}
+
+// NewGRPCServer creates a grpc server with grok interceptors and, when
+// configured, TLS credentials. It is meant to be passed to WithGRPC.
+func NewGRPCServer(settings *Settings, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	opts = append(GRPCServerOptions(restrictedFields(settings)), opts...)
+
+	if settings.GRPC != nil && settings.GRPC.TLS != nil {
+		config, err := NewTLSConfig(settings.GRPC.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+
+	return grpc.NewServer(opts...), nil
+}
+
 func WithGRPC(grpcServer *grpc.Server) APIOption {
 	return func(server *API) {
 		server.grpcServer = grpcServer
@@ -96,28 +181,119 @@ var defaultRestricteds = []string{
 	TransactionTokenHeader,
 }
 
-// New creates a new API server
+const (
+	defaultShutdownTimeout = 5 * time.Second
+)
+
+func restrictedFields(settings *Settings) []string {
+	restricteds := append([]string{}, defaultRestricteds...)
+	if settings.Log != nil {
+		restricteds = append(restricteds, settings.Log.Restricteds...)
+	}
+	return restricteds
+}
+
+func settingsLogOptions(settings *Settings) []LogOption {
+	if settings.Log == nil {
+		return nil
+	}
+	return []LogOption{WithRequestIDHeader(settings.Log.RequestIDHeader)}
+}
+
+// reloadable builds a handler from the settings, rebuilding it on each
+// reload when a settings watcher is set
+func (server *API) reloadable(build func(*Settings) gin.HandlerFunc) gin.HandlerFunc {
+	if server.watcher == nil {
+		return build(server.settings)
+	}
+	return server.watcher.Handler(build)
+}
+
+// currentSettings returns the settings with the reloaded values applied
+func (server *API) currentSettings() *Settings {
+	if server.watcher == nil {
+		return server.settings
+	}
+	return server.watcher.Settings()
+}
+
+// swaggerRoutes relaxes the security headers of the routes registered by
+// WithSwagger
+func (server *API) swaggerRoutes() map[string]map[string]string {
+	routes := map[string]map[string]string{
+		"/swagger": SwaggerSecurityHeaders,
+	}
+	if server.swagger != nil {
+		routes[server.swagger.path] = SwaggerSecurityHeaders
+	}
+	return routes
+}
+
+// New creates a new API server. It panics when the settings are invalid,
+// logging every problem found.
 func New(opts ...APIOption) *API {
-	server := &API{}
+	server := &API{ready: 1}
 	server.handlers = []gin.HandlerFunc{}
 
 	for _, opt := range opts {
 		opt(server)
 	}
 
+	if err := server.validateServerSettings(); err != nil {
+		logrus.WithError(err).Panic("invalid settings")
+	}
+
+	ApplyLogLevel(server.settings.Log)
+	if server.watcher != nil {
+		server.watcher.Subscribe(func(settings *Settings) {
+			ApplyLogLevel(settings.Log)
+		})
+	}
+
 	server.Engine = gin.New()
 	server.Engine.Use(gin.Recovery())
+
+	if server.problems {
+		server.Engine.Use(ProblemJSONMiddleware())
+	}
+
 	server.Engine.Use(SetMaxBodyBytesMiddleware(server.settings.API.MaxBodySize))
 
-	restricteds := defaultRestricteds
-	if server.settings.Log != nil {
-		restricteds = append(restricteds, server.settings.Log.Restricteds...)
+	if server.tracing {
+		if server.settings.Tracing != nil {
+			provider, err := CreateTracerProvider(server.settings.Tracing)
+			if err != nil {
+				logrus.WithError(err).Panic("error creating tracer provider")
+			}
+			server.tracerProvider = provider
+		}
+		server.Engine.Use(TracingMiddleware())
+	}
+
+	server.Engine.Use(server.reloadable(func(settings *Settings) gin.HandlerFunc {
+		return LogMiddleware(restrictedFields(settings), settingsLogOptions(settings)...)
+	}))
+
+	if server.settings.API.TLS != nil {
+		server.Engine.Use(PeerCertificateMiddleware())
+	}
+
+	if server.metrics != nil {
+		server.Engine.Use(server.metrics.Middleware())
 	}
 
-	server.Engine.Use(LogMiddleware(restricteds))
+	if server.security {
+		server.Engine.Use(SecurityHeadersMiddleware(server.settings.API.SecurityHeaders, server.swaggerRoutes()))
+	}
 
 	if server.cors {
-		server.Engine.Use(CORS())
+		server.Engine.Use(server.reloadable(func(settings *Settings) gin.HandlerFunc {
+			if settings.API.CORS != nil {
+				return CORSWithSettings(settings.API.CORS)
+			}
+			logrus.Warn("cors settings not found, denying cross-origin requests")
+			return CORSWithSettings(&CORSSettings{})
+		}))
 	}
 
 	server.Engine.NoRoute(func(c *gin.Context) {
@@ -126,8 +302,16 @@ func New(opts ...APIOption) *API {
 
 	server.router = server.Engine.Group("")
 
-	if server.healthz != nil {
-		server.router.GET("/healthz", server.healthz)
+	if server.settings.API.AdminHost != "" {
+		server.AdminEngine = server.newAdminEngine()
+	} else {
+		if server.healthz != nil {
+			server.router.GET("/healthz", server.readiness(), server.healthz)
+		}
+
+		if server.metrics != nil {
+			server.router.GET(MetricsPath, server.metrics.Handler())
+		}
 	}
 
 	server.router.GET("/swagger", Swagger(server.settings.API.Swagger))
@@ -145,24 +329,49 @@ func New(opts ...APIOption) *API {
 		ctrl.RegisterRoutes(server.router)
 	}
 
+	if err := server.validateRoutes(); err != nil {
+		logrus.WithError(err).Panic("invalid settings")
+	}
+
 	return server
 }
 
-func (server *API) runGRPC() {
+// Ready reports whether the server is accepting traffic. Servers are ready
+// once created, however Engine is served, and turn not ready as soon as Run
+// receives a shutdown signal.
+func (server *API) Ready() bool {
+	return atomic.LoadInt32(&server.ready) == 1
+}
+
+// readiness fails the request while the server is draining so load
+// balancers stop routing traffic before the listeners are closed.
+func (server *API) readiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !server.Ready() {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		c.Next()
+	}
+}
+
+func (server *API) runGRPC(errCh chan<- error) error {
 	if server.grpcServer == nil {
-		return
+		return nil
 	}
 	reflection.Register(server.grpcServer)
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s", server.settings.GRPC.Host))
+	server.registerGRPCHealth()
+	listener, err := net.Listen("tcp", server.settings.GRPC.Host)
 	if err != nil {
-		logrus.Fatalf("error binding address %s: %v", server.settings.GRPC.Host, err)
+		return fmt.Errorf("error binding address %s: %v", server.settings.GRPC.Host, err)
 	}
 	go func() {
 		logrus.Infof("GRPC server listening at %s", server.settings.GRPC.Host)
 		if err := server.grpcServer.Serve(listener); err != nil {
-			logrus.Fatalf("failed to serve: %v", err)
+			errCh <- fmt.Errorf("failed to serve grpc: %v", err)
 		}
 	}()
+	return nil
 }
 
 func (server *API) stopGRPC(ctx context.Context) {
@@ -184,35 +393,135 @@ func (server *API) stopGRPC(ctx context.Context) {
 	}
 }
 
-// Run starts the server.
-func (server *API) Run() {
-	defer server.Container.Close()
+func (server *API) listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+func (server *API) shutdownTimeout() time.Duration {
+	if server.settings.API.ShutdownTimeout > 0 {
+		return time.Duration(server.settings.API.ShutdownTimeout) * time.Second
+	}
+	return defaultShutdownTimeout
+}
 
+func (server *API) drainPeriod() time.Duration {
+	return time.Duration(server.settings.API.DrainPeriod) * time.Second
+}
+
+// Run starts the server and blocks until SIGTERM or SIGINT is received.
+// On shutdown the readiness check starts failing, the configured drain
+// period elapses and then HTTP, GRPC, the admin listener and the container
+// are stopped in order.
+func (server *API) Run() error {
 	srv := http.Server{
 		Addr:    server.settings.API.Host,
 		Handler: server.Engine,
 	}
 
-	sigs := make(chan os.Signal)
-	signal.Notify(sigs, os.Interrupt)
+	if server.settings.API.TLS != nil {
+		tlsConfig, err := NewTLSConfig(server.settings.API.TLS)
+		if err != nil {
+			server.Container.Close()
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	var adminSrv *http.Server
+	if server.AdminEngine != nil {
+		adminSrv = &http.Server{
+			Addr:    server.settings.API.AdminHost,
+			Handler: server.AdminEngine,
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	errCh := make(chan error, 3)
+
+	if err := server.runGRPC(errCh); err != nil {
+		server.Container.Close()
+		return err
+	}
+
+	server.runAdmin(adminSrv, errCh)
+
+	if server.watcher != nil {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go server.watcher.Run(watchCtx)
+	}
+
+	stopHealth := make(chan struct{})
+	go server.watchGRPCHealth(stopHealth)
 
 	go func() {
-		sig := <-sigs
+		logrus.Infof("start api %s", server.settings.API.Host)
+		if err := server.listenAndServe(&srv); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("startup error: %v", err)
+		}
+	}()
+
+	var runErr error
 
+	select {
+	case sig := <-sigs:
 		logrus.Infof("caught sig: %+v", sig)
-		logrus.Info("waiting 5 seconds to finish processing")
+	case runErr = <-errCh:
+		logrus.WithError(runErr).Error("server error")
+	}
+
+	atomic.StoreInt32(&server.ready, 0)
+	close(stopHealth)
+
+	if server.healthServer != nil {
+		server.healthServer.Shutdown()
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		server.stopGRPC(ctx)
-		if err := srv.Shutdown(ctx); err != nil {
-			logrus.WithField("error", err).Error("shutdown error")
+	if runErr == nil {
+		drain := server.drainPeriod()
+		logrus.Infof("draining for %s before shutdown", drain)
+		time.Sleep(drain)
+	}
+
+	timeout := server.shutdownTimeout()
+	logrus.Infof("waiting %s to finish processing", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Error("shutdown error")
+		if runErr == nil {
+			runErr = err
 		}
-	}()
-	server.runGRPC()
-	logrus.Infof("start api %s", server.settings.API.Host)
+	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logrus.WithField("error", err).Info("startup error")
+	server.stopGRPC(ctx)
+
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Error("admin shutdown error")
+		}
 	}
+
+	if err := server.Container.Close(); err != nil {
+		logrus.WithError(err).Error("error closing container")
+		if runErr == nil {
+			runErr = err
+		}
+	}
+
+	if server.tracerProvider != nil {
+		if err := server.tracerProvider.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Error("error flushing spans")
+		}
+	}
+
+	return runErr
 }
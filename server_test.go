@@ -0,0 +1,217 @@
+package grok_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type closeRecorderContainer struct {
+	closed chan bool
+}
+
+func (c *closeRecorderContainer) Controllers() []grok.APIController {
+	return nil
+}
+
+func (c *closeRecorderContainer) Close() error {
+	c.closed <- true
+	return nil
+}
+
+func TestReadyWithoutRun(t *testing.T) {
+	server := grok.New(
+		grok.WithSettings(&grok.Settings{API: &grok.APISettings{Host: "127.0.0.1:0"}}),
+		grok.WithHealthz(func(c *gin.Context) { c.Status(http.StatusOK) }),
+		grok.WithContainer(&closeRecorderContainer{}))
+
+	assert.True(t, server.Ready())
+
+	response := httptest.NewRecorder()
+	server.Engine.ServeHTTP(response, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestRunGracefulShutdown(t *testing.T) {
+	container := &closeRecorderContainer{closed: make(chan bool, 1)}
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			Host:            "127.0.0.1:0",
+			DrainPeriod:     1,
+			ShutdownTimeout: 1,
+		},
+	}
+
+	server := grok.New(
+		grok.WithSettings(settings),
+		grok.WithHealthz(func(c *gin.Context) { c.Status(http.StatusOK) }),
+		grok.WithContainer(container))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run()
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, server.Ready())
+
+	healthz := func() int {
+		response := httptest.NewRecorder()
+		server.Engine.ServeHTTP(response, httptest.NewRequest("GET", "/healthz", nil))
+		return response.Code
+	}
+
+	assert.Equal(t, http.StatusOK, healthz())
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, server.Ready())
+	assert.Equal(t, http.StatusServiceUnavailable, healthz())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+
+	assert.True(t, <-container.closed)
+}
+
+func TestRunStartupError(t *testing.T) {
+	container := &closeRecorderContainer{closed: make(chan bool, 1)}
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			Host: "invalid-host:-1",
+		},
+	}
+
+	server := grok.New(
+		grok.WithSettings(settings),
+		grok.WithContainer(container))
+
+	assert.Error(t, server.Run())
+	assert.True(t, <-container.closed)
+}
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestRunGRPCHealthFailingDependency(t *testing.T) {
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			Host: "127.0.0.1:0",
+		},
+		GRPC: &grok.GRPCSettings{
+			Host:                freeAddress(t),
+			HealthCheckInterval: 1,
+		},
+		Redis: &grok.RedisSettings{ConnectionString: "127.0.0.1:1"},
+	}
+
+	server := grok.New(
+		grok.WithSettings(settings),
+		grok.WithGRPC(grpc.NewServer()),
+		grok.WithGRPCHealthz(grok.NewHealthz(
+			grok.WithRedis(),
+			grok.WithHealthzSettings(settings))),
+		grok.WithContainer(&closeRecorderContainer{closed: make(chan bool, 1)}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run()
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	conn, err := grpc.Dial(settings.GRPC.Host, grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	assert.NoError(t, <-done)
+}
+
+func TestRunGRPCHealth(t *testing.T) {
+	var healthy int32 = 1
+
+	container := &closeRecorderContainer{closed: make(chan bool, 1)}
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			Host:        "127.0.0.1:0",
+			DrainPeriod: 1,
+		},
+		GRPC: &grok.GRPCSettings{
+			Host:                freeAddress(t),
+			HealthCheckInterval: 1,
+		},
+	}
+
+	server := grok.New(
+		grok.WithSettings(settings),
+		grok.WithGRPC(grpc.NewServer()),
+		grok.WithGRPCHealthz(grok.NewHealthz(grok.WithCheck(func(*grok.Healthz) error {
+			if atomic.LoadInt32(&healthy) == 0 {
+				return errors.New("unhealthy")
+			}
+			return nil
+		}))),
+		grok.WithContainer(container))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run()
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	conn, err := grpc.Dial(settings.GRPC.Host, grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return resp.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check())
+
+	atomic.StoreInt32(&healthy, 0)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check())
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+
+	assert.NoError(t, <-done)
+}
@@ -3,20 +3,28 @@ package grok
 import (
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Settings ...
 type Settings struct {
-	API          *APISettings   `yaml:"api"`
-	GRPC         *GRPCSettings  `yaml:"grpc"`
-	Mongo        *MongoSettings `yaml:"mongo"`
-	Redis        *RedisSettings `yaml:"redis"`
-	UserProvider *UserProvider  `yaml:"user_provider"`
-	Mail         *MailSettings  `yaml:"mail"`
-	AWS          *AWSSettings   `yaml:"aws"`
-	Log          *LogSettings   `yaml:"log"`
+	API          *APISettings         `yaml:"api"`
+	GRPC         *GRPCSettings        `yaml:"grpc"`
+	Mongo        *MongoSettings       `yaml:"mongo"`
+	Redis        *RedisSettings       `yaml:"redis"`
+	UserProvider *UserProvider        `yaml:"user_provider"`
+	Mail         *MailSettings        `yaml:"mail"`
+	AWS          *AWSSettings         `yaml:"aws"`
+	Log          *LogSettings         `yaml:"log"`
+	Tracing      *TracingSettings     `yaml:"tracing"`
+	RateLimit    *RateLimitSettings   `yaml:"rate_limit"`
+	Idempotency  *IdempotencySettings `yaml:"idempotency"`
+	Secrets      *SecretsSettings     `yaml:"secrets"`
+	Revocation   *RevocationSettings  `yaml:"revocation"`
+	APIKeys      *APIKeySettings      `yaml:"api_keys"`
+	Features     map[string]bool      `yaml:"features"` // reloaded by SettingsWatcher
 }
 
 // APISettings ...
@@ -28,27 +36,70 @@ type APISettings struct {
 	BaasProvider               *BaasProviderSettings       `yaml:"baas_provider"`
 	BaasProviderIntra          *BaasProviderIntraSettings  `yaml:"baas_provider_intra"`
 	TransactionalTokenSettings *TransactionalTokenSettings `yaml:"internal_transactional_token"`
-	MaxBodySize                int64                       `yaml:"max_body_size"`
+	MaxBodySize                int64                       `yaml:"max_body_size" validate:"gte=0"`
+	ShutdownTimeout            int64                       `yaml:"shutdown_timeout" validate:"gte=0"` // seconds, default 5
+	DrainPeriod                int64                       `yaml:"drain_period" validate:"gte=0"`     // seconds
+	TLS                        *TLSSettings                `yaml:"tls"`
+	AdminHost                  string                      `yaml:"admin_host"`       // serves healthz, metrics, pprof, settings and routes
+	CORS                       *CORSSettings               `yaml:"cors"`             // applied by WithCORS
+	SecurityHeaders            *SecurityHeadersSettings    `yaml:"security_headers"` // applied by WithSecurityHeaders
 }
 
 type GRPCSettings struct {
-	Host string `yaml:"host"`
+	Host                string       `yaml:"host" validate:"required"`
+	TLS                 *TLSSettings `yaml:"tls"`                                    // applied by NewGRPCServer
+	HealthCheckInterval int64        `yaml:"health_check_interval" validate:"gte=0"` // seconds, default 10
+}
+
+// CORSSettings ...
+type CORSSettings struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	MaxAge           int64    `yaml:"max_age" validate:"gte=0"` // seconds
+	AllowCredentials bool     `yaml:"allow_credentials"`
+}
+
+// SecurityHeadersSettings overrides DefaultSecurityHeaders, an empty value
+// removes the header
+type SecurityHeadersSettings struct {
+	Headers map[string]string            `yaml:"headers"`
+	Routes  map[string]map[string]string `yaml:"routes"` // path prefix to headers
 }
 
 // LogSettings ...
 type LogSettings struct {
-	Restricteds []string `yaml:"restricteds"`
+	Restricteds     []string `yaml:"restricteds"`
+	RequestIDHeader string   `yaml:"request_id_header"` // default X-Request-Id
+	Level           string   `yaml:"level" validate:"omitempty,oneof=panic fatal error warn warning info debug trace"`
 }
 
 // MongoSettings ...
 type MongoSettings struct {
 	CaFilePath       *string `yaml:"ca_file_path"`
-	ConnectionString string  `yaml:"connection_string"`
-	Database         string  `yaml:"database"`
+	ConnectionString string  `yaml:"connection_string" validate:"required"`
+	Database         string  `yaml:"database" validate:"required"`
 }
 
 type RedisSettings struct {
-	ConnectionString string `yaml:"connection_string"`
+	ConnectionString string `yaml:"connection_string" validate:"required"`
+}
+
+// RateLimitSettings ...
+type RateLimitSettings struct {
+	Fake   bool                  `yaml:"fake"`
+	Prefix string                `yaml:"prefix"`                          // redis key prefix, default rate_limit
+	Limits map[string]*RateLimit `yaml:"limits" validate:"dive,required"` // named limits, reloaded by SettingsWatcher.RateLimitMiddleware
+}
+
+// IdempotencySettings ...
+type IdempotencySettings struct {
+	Fake       bool   `yaml:"fake"`
+	Backend    string `yaml:"backend" validate:"omitempty,oneof=mongo redis"` // mongo (default) or redis
+	Collection string `yaml:"collection"`                                     // default idempotency_keys
+	TTL        int64  `yaml:"ttl" validate:"gte=0"`                           // seconds, default 24h
+	LockTTL    int64  `yaml:"lock_ttl" validate:"gte=0"`                      // seconds, default 30, lease of in progress requests
 }
 
 // AWSSettings ...
@@ -88,38 +139,43 @@ type SecretsManagerCredentials struct {
 
 // APIAuth ...
 type APIAuth struct {
-	Fake       bool         `yaml:"fake"`
-	FakeConfig *FakeAPIAuth `yaml:"fake_config"`
-	Tenant     string       `yaml:"tenant"`
-	JWKS       string       `yaml:"jwks"`
-	Audience   []string     `yaml:"audience"`
+	Fake                bool         `yaml:"fake"`
+	FakeConfig          *FakeAPIAuth `yaml:"fake_config" validate:"required_if=Fake true"`
+	Provider            string       `yaml:"provider" validate:"omitempty,oneof=auth0 oidc"` // auth0 (default) or oidc
+	Tenant              string       `yaml:"tenant" validate:"required_without_all=Fake Issuers"`
+	JWKS                string       `yaml:"jwks" validate:"required_without_all=Fake Issuers"`
+	Audience            []string     `yaml:"audience" validate:"required_if=Provider oidc Fake false"`
+	Issuers             []string     `yaml:"issuers" validate:"required_if=Provider oidc Fake false"` // oidc issuers, keys are discovered
+	Algorithms          []string     `yaml:"algorithms"`                                              // oidc, default RS256 and ES256
+	ClaimNamespace      string       `yaml:"claim_namespace"`                                         // oidc, default AuthClaimNamespace
+	JWKSRefreshInterval int64        `yaml:"jwks_refresh_interval" validate:"gte=0"`                  // seconds, default 3600
 }
 
 // InternalAuth ...
 type InternalAuth struct {
 	Fake    bool      `yaml:"fake"`
 	URL     *string   `yaml:"url"` // deprecated
-	URLs    []*string `yaml:"urls"`
+	URLs    []*string `yaml:"urls" validate:"required_without_all=Fake URL"`
 	Success *bool     `yaml:"success"`
 }
 
 // BaasProviderSettings ...
 type BaasProviderSettings struct {
 	Fake    bool    `yaml:"fake"`
-	URL     *string `yaml:"url"`
+	URL     *string `yaml:"url" validate:"required_unless=Fake true"`
 	Success *bool   `yaml:"success"`
 }
 
 // BaasProviderIntraSettings ...
 type BaasProviderIntraSettings struct {
 	Fake    bool    `yaml:"fake"`
-	URL     *string `yaml:"url"`
+	URL     *string `yaml:"url" validate:"required_unless=Fake true"`
 	Success *bool   `yaml:"success"`
 }
 
 type TransactionalTokenSettings struct {
 	Fake    bool   `yaml:"fake"`
-	URL     string `yaml:"url"`
+	URL     string `yaml:"url" validate:"required_unless=Fake true"`
 	Success *bool  `yaml:"success"`
 }
 
@@ -157,7 +213,9 @@ type MailSettings struct {
 	} `yaml:"send_grid"`
 }
 
-// FromYAML ...
+// FromYAML reads file into dist, interpolating ${VAR} references, applying
+// EnvPrefix environment overrides, resolving secret:// references with the
+// configured SecretStore and then validating it with ValidateSettings
 func FromYAML(file string, dist interface{}) error {
 	filename, _ := filepath.Abs(file)
 
@@ -167,5 +225,37 @@ func FromYAML(file string, dist interface{}) error {
 		return err
 	}
 
-	return yaml.Unmarshal(data, dist)
+	data, err = InterpolateEnv(data)
+
+	if err != nil {
+		return err
+	}
+
+	return loadYAML(data, dist)
+}
+
+// loadYAML unmarshals the interpolated data into dist, applies the
+// environment overrides, validates struct destinations and resolves the
+// secrets, once the secrets settings are known to be valid
+func loadYAML(data []byte, dist interface{}) error {
+	if err := yaml.Unmarshal(data, dist); err != nil {
+		return err
+	}
+
+	if err := ApplyEnvOverrides(EnvPrefix, dist); err != nil {
+		return err
+	}
+
+	if isStructPointer(dist) {
+		if err := ValidateSettings(dist); err != nil {
+			return err
+		}
+	}
+
+	return resolveSettingsSecrets(dist)
+}
+
+func isStructPointer(dist interface{}) bool {
+	t := reflect.TypeOf(dist)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
 }
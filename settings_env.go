@@ -0,0 +1,225 @@
+package grok
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var (
+	// EnvPrefix prefixes the environment variables overriding settings, e.g.
+	// GROK_API_HOST overrides api.host
+	EnvPrefix = "GROK"
+
+	envReference = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|:\?)([^}]*))?\}`)
+	envName      = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// InterpolateEnv replaces ${VAR} and ${VAR:-default} with environment
+// variables. ${VAR:?message} fails when VAR is unset or empty and $${ is
+// kept as a literal ${. References are replaced in the parsed scalars, so
+// values containing yaml syntax, e.g. # or ": ", are kept as they are.
+func InterpolateEnv(data []byte) ([]byte, error) {
+	document := new(yamlv3.Node)
+	if err := yamlv3.Unmarshal(data, document); err != nil {
+		return nil, err
+	}
+
+	if document.Kind == 0 {
+		return data, nil
+	}
+
+	if err := interpolateNode(document); err != nil {
+		return nil, err
+	}
+
+	return yamlv3.Marshal(document)
+}
+
+func interpolateNode(node *yamlv3.Node) error {
+	if node.Kind == yamlv3.ScalarNode {
+		value, err := interpolateEnv(node.Value)
+		if err != nil {
+			return err
+		}
+
+		if value != node.Value {
+			node.Value = value
+			// plain scalars have their type resolved from the new value, the
+			// encoder quotes it when it is not a valid plain scalar
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := interpolateNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func interpolateEnv(data string) (string, error) {
+	var err error
+
+	result := envReference.ReplaceAllStringFunc(data, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		groups := envReference.FindStringSubmatch(match)
+		name, operator, argument := groups[1], groups[2], groups[3]
+
+		value, found := os.LookupEnv(name)
+		if found && value != "" {
+			return value
+		}
+
+		switch operator {
+		case ":-":
+			return argument
+		case ":?":
+			if err == nil {
+				err = fmt.Errorf("environment variable %s is required: %s", name, argument)
+			}
+		}
+
+		return value
+	})
+
+	return result, err
+}
+
+// ApplyEnvOverrides sets the fields of dist, a pointer to a settings struct,
+// from environment variables named after the yaml path of the field, e.g.
+// PREFIX_API_HOST for api.host. Lists and maps use yaml flow syntax, e.g.
+// [a, b].
+func ApplyEnvOverrides(prefix string, dist interface{}) error {
+	value := reflect.ValueOf(dist)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("settings must be a non-nil pointer")
+	}
+
+	_, err := applyEnvOverrides(envKey(prefix), value.Elem(), environ())
+	return err
+}
+
+// applyEnvOverrides reports whether any field was set so nil struct pointers
+// are only allocated when one of their fields is overridden
+func applyEnvOverrides(name string, value reflect.Value, env map[string]string) (bool, error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			if !value.IsNil() {
+				return applyEnvOverrides(name, value.Elem(), env)
+			}
+			if !hasEnvPrefix(env, name+"_") {
+				return false, nil
+			}
+
+			allocated := reflect.New(value.Type().Elem())
+			set, err := applyEnvOverrides(name, allocated.Elem(), env)
+			if set {
+				value.Set(allocated)
+			}
+			return set, err
+		}
+	case reflect.Struct:
+		return applyStructEnvOverrides(name, value, env)
+	}
+
+	raw, found := env[name]
+	if !found {
+		return false, nil
+	}
+
+	if value.Kind() == reflect.String {
+		value.SetString(raw)
+		return true, nil
+	}
+
+	target := reflect.New(value.Type())
+	if err := yaml.Unmarshal([]byte(raw), target.Interface()); err != nil {
+		return false, fmt.Errorf("invalid value for %s: %v", name, err)
+	}
+	value.Set(target.Elem())
+
+	return true, nil
+}
+
+func applyStructEnvOverrides(name string, value reflect.Value, env map[string]string) (bool, error) {
+	set := false
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		fieldName := name
+		if !contains(tag[1:], "inline") {
+			key := tag[0]
+			if key == "" {
+				key = strings.ToLower(field.Name)
+			}
+			fieldName = envKey(key)
+			if name != "" {
+				fieldName = name + "_" + fieldName
+			}
+		}
+
+		fieldSet, err := applyEnvOverrides(fieldName, value.Field(i), env)
+		if err != nil {
+			return set, err
+		}
+		set = set || fieldSet
+	}
+
+	return set, nil
+}
+
+func envKey(key string) string {
+	return strings.Trim(envName.ReplaceAllString(strings.ToUpper(key), "_"), "_")
+}
+
+func environ() map[string]string {
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
+
+func hasEnvPrefix(env map[string]string, prefix string) bool {
+	for key := range env {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
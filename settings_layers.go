@@ -0,0 +1,143 @@
+package grok
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// LocalOverlay names the developer overlay applied after the environment
+	// one, e.g. config.local.yaml. It is meant to be kept out of version
+	// control.
+	LocalOverlay = "local"
+)
+
+// LayeredFiles returns file followed by its environment and local overlays
+// that exist, e.g. config.yaml, config.prod.yaml and config.local.yaml
+func LayeredFiles(file string, environment string) []string {
+	files := []string{file}
+
+	ext := filepath.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+
+	overlays := []string{}
+	if environment != "" {
+		overlays = append(overlays, environment)
+	}
+	overlays = append(overlays, LocalOverlay)
+
+	for _, overlay := range overlays {
+		name := fmt.Sprintf("%s.%s%s", base, overlay, ext)
+		if _, err := os.Stat(name); err == nil {
+			files = append(files, name)
+		}
+	}
+
+	return files
+}
+
+// FromLayeredYAML loads file merged with its environment and local overlays,
+// see LayeredFiles and FromYAMLFiles
+func FromLayeredYAML(file string, environment string, dist interface{}) error {
+	return FromYAMLFiles(LayeredFiles(file, environment), dist)
+}
+
+// FromYAMLFiles deep merges files into dist, later files taking precedence,
+// and then applies the same steps as FromYAML. Maps are merged key by key
+// while lists, e.g. log.restricteds or api.internal_auth.urls, and scalars
+// are replaced as a whole. A null value clears the key.
+func FromYAMLFiles(files []string, dist interface{}) error {
+	if len(files) == 0 {
+		return fmt.Errorf("at least one settings file is required")
+	}
+
+	var merged interface{}
+
+	for _, file := range files {
+		layer, err := readYAMLLayer(file)
+		if err != nil {
+			return err
+		}
+		merged = MergeYAML(merged, layer)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	return loadYAML(data, dist)
+}
+
+func readYAMLLayer(file string) (interface{}, error) {
+	filename, _ := filepath.Abs(file)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = InterpolateEnv(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	var layer interface{}
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	return layer, nil
+}
+
+// MergeYAML deep merges the overlay map into base. Any other overlay value
+// replaces base and an empty overlay keeps it.
+func MergeYAML(base interface{}, overlay interface{}) interface{} {
+	baseMap, baseOK := base.(map[interface{}]interface{})
+	overlayMap, overlayOK := overlay.(map[interface{}]interface{})
+
+	if !baseOK || !overlayOK {
+		if overlay == nil {
+			return base
+		}
+		return overlay
+	}
+
+	result := make(map[interface{}]interface{}, len(baseMap)+len(overlayMap))
+	for key, value := range baseMap {
+		result[key] = value
+	}
+
+	for key, value := range overlayMap {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = MergeYAML(result[key], value)
+	}
+
+	return result
+}
+
+// PrintSettings writes settings as yaml with the sensitive and restricted
+// keys redacted, see RedactSettings
+func PrintSettings(w io.Writer, settings interface{}, restricteds []string) error {
+	redactedSettings, err := RedactSettings(settings, restricteds)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(redactedSettings)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,214 @@
+package grok
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// SettingsError lists every problem found validating the settings
+type SettingsError struct {
+	Problems []string
+}
+
+// Error ...
+func (e *SettingsError) Error() string {
+	return fmt.Sprintf("invalid settings: %s", strings.Join(e.Problems, "; "))
+}
+
+// ValidateSettings validates settings, a pointer to a settings struct, with
+// the validate tags of its fields. Blocks that are not configured are not
+// validated. Problems are reported by yaml path, e.g. api.auth.fake_config.
+func ValidateSettings(settings interface{}) error {
+	problems, err := settingsProblems(settings)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return &SettingsError{Problems: problems}
+	}
+
+	return nil
+}
+
+func settingsProblems(settings interface{}) ([]string, error) {
+	err := Validator.Struct(settings)
+	if err == nil {
+		return nil, nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return nil, err
+	}
+
+	root := reflect.TypeOf(settings)
+	for root.Kind() == reflect.Ptr {
+		root = root.Elem()
+	}
+
+	problems := make([]string, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		path, parent := yamlPath(root, e.StructNamespace())
+		problems = append(problems, settingsMessage(path, parent, e))
+	}
+
+	return problems, nil
+}
+
+// validateServerSettings checks the settings New depends on besides the
+// ones ValidateSettings covers
+func (server *API) validateServerSettings() error {
+	if server.settings == nil {
+		return &SettingsError{Problems: []string{"settings are required"}}
+	}
+
+	problems := []string{}
+
+	if server.settings.API == nil {
+		problems = append(problems, "api is required")
+	}
+
+	if server.grpcServer != nil && server.settings.GRPC == nil {
+		problems = append(problems, "grpc is required when WithGRPC is used")
+	}
+
+	more, err := settingsProblems(server.settings)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, more...)
+
+	if len(problems) > 0 {
+		return &SettingsError{Problems: problems}
+	}
+
+	return nil
+}
+
+// validateRoutes checks the settings required by the registered routes.
+// The internal authorization resolves the :account_id identity with the
+// second internal auth url.
+func (server *API) validateRoutes() error {
+	auth := server.settings.API.InternalAuth
+	if auth == nil || auth.Fake || len(auth.URLs) >= 2 {
+		return nil
+	}
+
+	for _, route := range server.Engine.Routes() {
+		if strings.Contains(route.Path, "/:"+ACCOUNT_ID_PARAM) {
+			return &SettingsError{Problems: []string{
+				fmt.Sprintf("api.internal_auth.urls must have at least 2 urls, %s %s uses :%s",
+					route.Method, route.Path, ACCOUNT_ID_PARAM),
+			}}
+		}
+	}
+
+	return nil
+}
+
+// yamlPath converts a struct namespace, e.g. Settings.API.Auth.FakeConfig,
+// into the yaml path api.auth.fake_config and returns the struct type
+// holding the last field
+func yamlPath(root reflect.Type, namespace string) (string, reflect.Type) {
+	parts := strings.Split(namespace, ".")
+	if root.Name() != "" {
+		parts = parts[1:]
+	}
+	path := []string{}
+	current := root
+	parent := root
+
+	for _, part := range parts {
+		name, index := part, ""
+		if i := strings.Index(part, "["); i >= 0 {
+			name, index = part[:i], part[i:]
+		}
+
+		for current.Kind() == reflect.Ptr {
+			current = current.Elem()
+		}
+
+		field, found := reflect.StructField{}, false
+		if current.Kind() == reflect.Struct {
+			field, found = current.FieldByName(name)
+		}
+
+		if !found {
+			path = append(path, strings.ToLower(name)+index)
+			continue
+		}
+
+		parent = current
+		if key := yamlKey(field); key != "" {
+			path = append(path, key+index)
+		}
+
+		current = field.Type
+		if index != "" {
+			current = current.Elem()
+		}
+	}
+
+	return strings.Join(path, "."), parent
+}
+
+// yamlKey returns the yaml key of field, empty for inlined fields
+func yamlKey(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")
+	if contains(tag[1:], "inline") {
+		return ""
+	}
+	if tag[0] != "" {
+		return tag[0]
+	}
+	return strings.ToLower(field.Name)
+}
+
+func settingsMessage(path string, parent reflect.Type, e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", path)
+	case "required_if":
+		return fmt.Sprintf("%s is required when %s", path, conditions(parent, e.Param(), " and "))
+	case "required_unless":
+		return fmt.Sprintf("%s is required unless %s", path, conditions(parent, e.Param(), " or "))
+	case "required_without_all":
+		return fmt.Sprintf("%s is required unless one of %s is set", path, strings.Join(yamlKeys(parent, strings.Fields(e.Param())), ", "))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", path, strings.Join(strings.Fields(e.Param()), ", "))
+	case "gte", "min":
+		return fmt.Sprintf("%s must be greater than or equal to %s", path, e.Param())
+	default:
+		return fmt.Sprintf("%s failed on %s", path, e.Tag())
+	}
+}
+
+// conditions describes the "Field value" pairs of required_if and
+// required_unless with yaml keys
+func conditions(parent reflect.Type, param string, separator string) string {
+	fields := strings.Fields(param)
+	result := []string{}
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		key := yamlKeys(parent, fields[i:i+1])[0]
+		result = append(result, fmt.Sprintf("%s is %s", key, fields[i+1]))
+	}
+
+	return strings.Join(result, separator)
+}
+
+func yamlKeys(parent reflect.Type, names []string) []string {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if field, found := parent.FieldByName(name); found && yamlKey(field) != "" {
+			key = yamlKey(field)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
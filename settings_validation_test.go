@@ -0,0 +1,147 @@
+package grok_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type accountContainer struct{}
+
+func (c *accountContainer) Controllers() []grok.APIController {
+	return []grok.APIController{c}
+}
+
+func (c *accountContainer) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/accounts/:account_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+}
+
+func (c *accountContainer) Close() error {
+	return nil
+}
+
+func TestValidateSettings(t *testing.T) {
+	url := "http://auth"
+
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			MaxBodySize:  -1,
+			Auth:         &grok.APIAuth{Fake: true},
+			InternalAuth: &grok.InternalAuth{},
+			BaasProvider: &grok.BaasProviderSettings{Fake: true},
+		},
+		GRPC:        &grok.GRPCSettings{},
+		Mongo:       &grok.MongoSettings{ConnectionString: "mongodb://localhost:27017"},
+		Idempotency: &grok.IdempotencySettings{Backend: "memcached"},
+	}
+
+	err := grok.ValidateSettings(settings)
+
+	assert.IsType(t, &grok.SettingsError{}, err)
+	assert.ElementsMatch(t, []string{
+		"api.max_body_size must be greater than or equal to 0",
+		"api.auth.fake_config is required when fake is true",
+		"api.internal_auth.urls is required unless one of fake, url is set",
+		"grpc.host is required",
+		"mongo.database is required",
+		"idempotency.backend must be one of mongo, redis",
+	}, err.(*grok.SettingsError).Problems)
+
+	settings.API.MaxBodySize = 0
+	settings.API.Auth.FakeConfig = &grok.FakeAPIAuth{}
+	settings.API.InternalAuth.URL = &url
+	settings.GRPC.Host = ":9001"
+	settings.Mongo.Database = "grok"
+	settings.Idempotency.Backend = "redis"
+
+	assert.NoError(t, grok.ValidateSettings(settings))
+
+	settings.API.Auth.Fake = false
+	assert.EqualError(t, grok.ValidateSettings(settings),
+		"invalid settings: api.auth.tenant is required unless one of fake, issuers is set; api.auth.jwks is required unless one of fake, issuers is set")
+}
+
+func TestFromYAMLValidation(t *testing.T) {
+	file := writeSettings(t, `
+api:
+  host: :9000
+redis: {}
+`)
+
+	err := grok.FromYAML(file, &grok.Settings{})
+	assert.EqualError(t, err, "invalid settings: redis.connection_string is required")
+
+	wrapped := &struct {
+		Grok *grok.Settings `yaml:"grok"`
+	}{}
+
+	file = writeSettings(t, `
+grok:
+  mongo:
+    database: grok
+`)
+
+	err = grok.FromYAML(file, wrapped)
+	assert.EqualError(t, err, "invalid settings: grok.mongo.connection_string is required")
+}
+
+func TestFromYAMLOIDCRequiresAudience(t *testing.T) {
+	file := writeSettings(t, `
+api:
+  host: :9000
+  auth:
+    provider: oidc
+    issuers: [https://issuer.contbank.com/]
+`)
+
+	err := grok.FromYAML(file, &grok.Settings{})
+	assert.EqualError(t, err, "invalid settings: api.auth.audience is required when provider is oidc and fake is false")
+}
+
+func TestFromYAMLNonStruct(t *testing.T) {
+	file := writeSettings(t, `
+mongo:
+  database: grok
+`)
+
+	settings := map[string]interface{}{}
+	assert.NoError(t, grok.FromYAML(file, &settings))
+	assert.Contains(t, settings, "mongo")
+}
+
+func TestFromYAMLValidatesSecretsBeforeResolving(t *testing.T) {
+	file := writeSettings(t, `
+secrets:
+  backend: file
+redis:
+  connection_string: secret://redis
+`)
+
+	err := grok.FromYAML(file, &grok.Settings{})
+	assert.EqualError(t, err, "invalid settings: secrets.path is required when backend is file")
+}
+
+func TestNewInvalidSettings(t *testing.T) {
+	assert.Panics(t, func() {
+		grok.New(grok.WithSettings(&grok.Settings{}), grok.WithContainer(&accountContainer{}))
+	})
+
+	settings := &grok.Settings{
+		API: &grok.APISettings{
+			InternalAuth: &grok.InternalAuth{URLs: []*string{new(string)}},
+		},
+	}
+
+	assert.Panics(t, func() {
+		grok.New(grok.WithSettings(settings), grok.WithContainer(&accountContainer{}))
+	})
+
+	settings.API.InternalAuth.URLs = append(settings.API.InternalAuth.URLs, new(string))
+
+	assert.NotPanics(t, func() {
+		grok.New(grok.WithSettings(settings), grok.WithContainer(&accountContainer{}))
+	})
+}
@@ -0,0 +1,382 @@
+package grok
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultWatchInterval = 5 * time.Second
+)
+
+var (
+	// ReloadableSettings are the settings paths, and everything under them,
+	// applied by SettingsWatcher without a restart. Changes to any other
+	// setting are reported by RestartRequired.
+	ReloadableSettings = []string{
+		"log.restricteds",
+		"log.level",
+		"api.cors.allowed_origins",
+		"rate_limit.limits",
+		"features",
+	}
+)
+
+// SettingsWatcher reloads the settings files when they change or on SIGHUP.
+// Reloaded settings are validated and only their ReloadableSettings are
+// applied, then subscribers are notified with the new settings.
+type SettingsWatcher struct {
+	files    []string
+	interval time.Duration
+
+	mutex           sync.Mutex
+	current         atomic.Value
+	subscribers     []func(*Settings)
+	stamps          map[string]fileStamp
+	restartRequired []string
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// WatcherOption ...
+type WatcherOption func(*SettingsWatcher)
+
+// WithWatchInterval sets how often the files are checked for changes,
+// default 5s
+func WithWatchInterval(interval time.Duration) WatcherOption {
+	return func(w *SettingsWatcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// NewSettingsWatcher loads files with FromYAMLFiles
+func NewSettingsWatcher(files []string, opts ...WatcherOption) (*SettingsWatcher, error) {
+	w := &SettingsWatcher{
+		files:    files,
+		interval: defaultWatchInterval,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	w.stamps = w.fileStamps()
+
+	settings := &Settings{}
+	if err := FromYAMLFiles(files, settings); err != nil {
+		return nil, err
+	}
+
+	w.current.Store(settings)
+
+	return w, nil
+}
+
+// Settings returns the current settings. They must not be modified.
+func (w *SettingsWatcher) Settings() *Settings {
+	return w.current.Load().(*Settings)
+}
+
+// Subscribe calls fn with the new settings after each reload changing them
+func (w *SettingsWatcher) Subscribe(fn func(*Settings)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// RestartRequired returns the settings paths that changed in the files but
+// are not applied until a restart
+func (w *SettingsWatcher) RestartRequired() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return append([]string{}, w.restartRequired...)
+}
+
+// Handler returns a handler built from the current settings and rebuilt on
+// each reload, e.g. to pick up new log restricteds or cors origins
+func (w *SettingsWatcher) Handler(build func(*Settings) gin.HandlerFunc) gin.HandlerFunc {
+	var handler atomic.Value
+	handler.Store(build(w.Settings()))
+
+	w.Subscribe(func(settings *Settings) {
+		handler.Store(build(settings))
+	})
+
+	return func(c *gin.Context) {
+		handler.Load().(gin.HandlerFunc)(c)
+	}
+}
+
+// RateLimitMiddleware rate limits with the limit name of the current
+// settings as NamedRateLimitMiddleware does, applying reloaded limits to the
+// next requests. A reload removing the limit keeps the previous one.
+func (w *SettingsWatcher) RateLimitMiddleware(store RateLimitStore, name string) gin.HandlerFunc {
+	var handler atomic.Value
+	handler.Store(NamedRateLimitMiddleware(store, w.Settings(), name))
+
+	w.Subscribe(func(settings *Settings) {
+		limit, err := namedRateLimit(settings, name)
+		if err != nil {
+			logrus.WithError(err).Error("keeping the previous rate limit")
+			return
+		}
+		handler.Store(RateLimitMiddleware(store, limit))
+	})
+
+	return func(c *gin.Context) {
+		handler.Load().(gin.HandlerFunc)(c)
+	}
+}
+
+// Run reloads the settings when the files change or SIGHUP is received
+// until ctx is done. Failed reloads are logged and keep the current
+// settings.
+func (w *SettingsWatcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.reload("signal")
+		case <-ticker.C:
+			if w.modified() {
+				w.reload("file change")
+			}
+		}
+	}
+}
+
+func (w *SettingsWatcher) reload(reason string) {
+	if err := w.Reload(); err != nil {
+		logrus.WithError(err).
+			WithField("reason", reason).
+			Error("error reloading settings")
+	}
+}
+
+// Reload loads and validates the files and applies the reloadable changes.
+// Subscribers are notified after the lock is released so they can use the
+// watcher, e.g. call Settings or Subscribe.
+func (w *SettingsWatcher) Reload() error {
+	settings, subscribers, err := w.apply()
+	if err != nil || settings == nil {
+		return err
+	}
+
+	for _, subscriber := range subscribers {
+		subscriber(settings)
+	}
+
+	return nil
+}
+
+// apply stores the reloaded settings, returning them and the subscribers to
+// notify, or nil settings when nothing reloadable changed
+func (w *SettingsWatcher) apply() (*Settings, []func(*Settings), error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.stamps = w.fileStamps()
+
+	loaded := &Settings{}
+	if err := FromYAMLFiles(w.files, loaded); err != nil {
+		return nil, nil, err
+	}
+
+	current, err := settingsMap(w.Settings())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, err := settingsMap(loaded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied := []string{}
+	w.restartRequired = []string{}
+
+	for _, path := range changedPaths(current, next) {
+		if !reloadable(path) {
+			w.restartRequired = append(w.restartRequired, path)
+			continue
+		}
+
+		value, _ := lookupPath(next, path)
+		current = setPath(current, strings.Split(path, "."), value)
+		applied = append(applied, path)
+	}
+
+	if len(w.restartRequired) > 0 {
+		logrus.WithField("settings", w.restartRequired).
+			Warn("settings changes require a restart")
+	}
+
+	if len(applied) == 0 {
+		return nil, nil, nil
+	}
+
+	data, err := yaml.Marshal(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings := &Settings{}
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, nil, err
+	}
+
+	w.current.Store(settings)
+
+	logrus.WithField("settings", applied).Info("settings reloaded")
+
+	return settings, append([]func(*Settings){}, w.subscribers...), nil
+}
+
+func (w *SettingsWatcher) modified() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return !reflect.DeepEqual(w.stamps, w.fileStamps())
+}
+
+func (w *SettingsWatcher) fileStamps() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, file := range w.files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// Feature reports whether the feature toggle name is enabled
+func (settings *Settings) Feature(name string) bool {
+	return settings.Features[name]
+}
+
+func reloadable(path string) bool {
+	for _, prefix := range ReloadableSettings {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func settingsMap(settings *Settings) (map[interface{}]interface{}, error) {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// changedPaths returns the sorted paths of the leaves that differ. Lists
+// and empty maps are leaves.
+func changedPaths(current, next map[interface{}]interface{}) []string {
+	currentLeaves := map[string]interface{}{}
+	nextLeaves := map[string]interface{}{}
+
+	flattenYAML("", current, currentLeaves)
+	flattenYAML("", next, nextLeaves)
+
+	paths := []string{}
+	for path, value := range currentLeaves {
+		if other, found := nextLeaves[path]; !found || !reflect.DeepEqual(value, other) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range nextLeaves {
+		if _, found := currentLeaves[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func flattenYAML(path string, value interface{}, leaves map[string]interface{}) {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok || (len(m) == 0 && path != "") {
+		leaves[path] = value
+		return
+	}
+
+	for key, item := range m {
+		flattenYAML(joinPath(path, fmt.Sprint(key)), item, leaves)
+	}
+}
+
+func lookupPath(m map[interface{}]interface{}, path string) (interface{}, bool) {
+	var value interface{} = m
+
+	for _, key := range strings.Split(path, ".") {
+		current, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = current[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// setPath sets value at path, creating the intermediate maps, and deletes
+// the key when value is nil
+func setPath(m map[interface{}]interface{}, path []string, value interface{}) map[interface{}]interface{} {
+	if m == nil {
+		m = map[interface{}]interface{}{}
+	}
+
+	if len(path) == 1 {
+		if value == nil {
+			delete(m, path[0])
+		} else {
+			m[path[0]] = value
+		}
+		return m
+	}
+
+	child, _ := m[path[0]].(map[interface{}]interface{})
+	m[path[0]] = setPath(child, path[1:], value)
+
+	return m
+}
@@ -0,0 +1,250 @@
+package grok_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const watchedSettings = `
+api:
+  host: :9000
+log:
+  restricteds: [password]
+features:
+  new_checkout: false
+`
+
+func TestSettingsWatcherReload(t *testing.T) {
+	file := writeSettings(t, watchedSettings)
+
+	watcher, err := grok.NewSettingsWatcher([]string{file})
+	assert.NoError(t, err)
+
+	notified := 0
+	watcher.Subscribe(func(*grok.Settings) { notified++ })
+
+	initial := watcher.Settings()
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+api:
+  host: :8000
+log:
+  restricteds: [password, document]
+  level: warn
+features:
+  new_checkout: true
+`), 0600))
+
+	assert.NoError(t, watcher.Reload())
+
+	settings := watcher.Settings()
+	assert.Equal(t, 1, notified)
+	assert.Equal(t, []string{"password", "document"}, settings.Log.Restricteds)
+	assert.Equal(t, "warn", settings.Log.Level)
+	assert.True(t, settings.Feature("new_checkout"))
+	assert.Equal(t, ":9000", settings.API.Host)
+	assert.Equal(t, []string{"api.host"}, watcher.RestartRequired())
+
+	assert.Equal(t, []string{"password"}, initial.Log.Restricteds)
+	assert.False(t, initial.Feature("new_checkout"))
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+api:
+  host: :8000
+log:
+  level: verbose
+`), 0600))
+
+	assert.Error(t, watcher.Reload())
+	assert.Equal(t, settings, watcher.Settings())
+	assert.Equal(t, 1, notified)
+}
+
+func TestSettingsWatcherSubscriberUsesWatcher(t *testing.T) {
+	file := writeSettings(t, watchedSettings)
+
+	watcher, err := grok.NewSettingsWatcher([]string{file})
+	assert.NoError(t, err)
+
+	restartRequired := []string{}
+	watcher.Subscribe(func(*grok.Settings) {
+		restartRequired = watcher.RestartRequired()
+		watcher.Subscribe(func(*grok.Settings) {})
+	})
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+api:
+  host: :8000
+log:
+  restricteds: [password]
+features:
+  new_checkout: true
+`), 0600))
+
+	done := make(chan error)
+	go func() { done <- watcher.Reload() }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber deadlocked the reload")
+	}
+
+	assert.Equal(t, []string{"api.host"}, restartRequired)
+}
+
+func TestSettingsWatcherRateLimit(t *testing.T) {
+	file := writeSettings(t, `
+rate_limit:
+  fake: true
+  limits:
+    transfers:
+      limit: 1
+      period: 60
+`)
+
+	watcher, err := grok.NewSettingsWatcher([]string{file})
+	assert.NoError(t, err)
+
+	engine := gin.New()
+	engine.GET("/transfers",
+		watcher.RateLimitMiddleware(grok.CreateRateLimitStore(watcher.Settings()), "transfers"),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func() *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest("GET", "/transfers", nil))
+		return response
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+	assert.Equal(t, http.StatusTooManyRequests, request().Code)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+rate_limit:
+  fake: true
+  limits:
+    transfers:
+      limit: 100
+      period: 1
+`), 0600))
+
+	assert.NoError(t, watcher.Reload())
+	assert.Empty(t, watcher.RestartRequired())
+
+	// the counters are kept, the new limit refills the bucket in 10ms
+	time.Sleep(50 * time.Millisecond)
+
+	response := request()
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "100", response.Header().Get("RateLimit-Limit"))
+}
+
+func TestSettingsWatcherRun(t *testing.T) {
+	file := writeSettings(t, watchedSettings)
+
+	watcher, err := grok.NewSettingsWatcher([]string{file}, grok.WithWatchInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	reloaded := make(chan *grok.Settings, 1)
+	watcher.Subscribe(func(settings *grok.Settings) { reloaded <- settings })
+
+	handler := watcher.Handler(func(settings *grok.Settings) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if settings.Feature("new_checkout") {
+				c.Status(http.StatusOK)
+				return
+			}
+			c.Status(http.StatusNotFound)
+		}
+	})
+
+	engine := gin.New()
+	engine.GET("/checkout", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checkout", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+api:
+  host: :9000
+log:
+  restricteds: [password]
+features:
+  new_checkout: true
+`), 0600))
+
+	select {
+	case settings := <-reloaded:
+		assert.True(t, settings.Feature("new_checkout"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("settings not reloaded")
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checkout", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWithSettingsWatcher(t *testing.T) {
+	level := logrus.GetLevel()
+	defer logrus.SetLevel(level)
+
+	file := writeSettings(t, `
+api:
+  host: :9000
+  cors:
+    allowed_origins: [https://app.contbank.com]
+log:
+  level: info
+`)
+
+	watcher, err := grok.NewSettingsWatcher([]string{file})
+	assert.NoError(t, err)
+
+	server := grok.New(
+		grok.WithSettingsWatcher(watcher),
+		grok.WithCORS(),
+		grok.WithContainer(&accountContainer{}))
+
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+
+	preflight := func(origin string) int {
+		req := httptest.NewRequest(http.MethodOptions, "/accounts/1", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		server.Engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, preflight("https://admin.contbank.com"))
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+api:
+  host: :9000
+  cors:
+    allowed_origins: [https://app.contbank.com, https://admin.contbank.com]
+log:
+  level: error
+`), 0600))
+	assert.NoError(t, watcher.Reload())
+
+	assert.Equal(t, http.StatusNoContent, preflight("https://admin.contbank.com"))
+	assert.Equal(t, logrus.ErrorLevel, logrus.GetLevel())
+}
@@ -1,17 +1,22 @@
 package grok
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MessageBrokerSubscriber ...
@@ -19,12 +24,14 @@ type MessageBrokerSubscriber struct {
 	sqsSvc       *sqs.SQS
 	snsSvc       *sns.SNS
 	handler      func(interface{}) error
+	ctxHandler   func(context.Context, interface{}) error
 	subscriberID string
 	topicIDs     []string
 	handleType   reflect.Type
 	maxRetries   int
 	fifo         bool
 	dlq          bool
+	metrics      *Metrics
 }
 
 // MessageBrokerSubscriberOption ...
@@ -37,6 +44,7 @@ func NewMessageBrokerSubscriber(opts ...MessageBrokerSubscriberOption) *MessageB
 	subscriber.topicIDs = make([]string, 0)
 	subscriber.fifo = false
 	subscriber.dlq = true
+	subscriber.metrics = DefaultMetrics
 
 	for _, opt := range opts {
 		opt(subscriber)
@@ -59,13 +67,21 @@ func WithSessionSNS(sessionSNS *session.Session) MessageBrokerSubscriberOption {
 	}
 }
 
-// WithHandler ...
+// WithHandler receives only the message, the request id propagated by the
+// producer is logged but not passed on. Use WithContextHandler.
 func WithHandler(h func(interface{}) error) MessageBrokerSubscriberOption {
 	return func(s *MessageBrokerSubscriber) {
 		s.handler = h
 	}
 }
 
+// WithContextHandler receives the trace context propagated by the producer
+func WithContextHandler(h func(context.Context, interface{}) error) MessageBrokerSubscriberOption {
+	return func(s *MessageBrokerSubscriber) {
+		s.ctxHandler = h
+	}
+}
+
 // WithSubscriberID ...
 func WithSubscriberID(id string) MessageBrokerSubscriberOption {
 	return func(s *MessageBrokerSubscriber) {
@@ -108,6 +124,13 @@ func WithDLQ(dlq bool) MessageBrokerSubscriberOption {
 	}
 }
 
+// WithSubscriberMetrics - default DefaultMetrics
+func WithSubscriberMetrics(metrics *Metrics) MessageBrokerSubscriberOption {
+	return func(s *MessageBrokerSubscriber) {
+		s.metrics = metrics
+	}
+}
+
 // WithFIFOAttributes ...
 func WithFIFOAttributes(messageGroupID *string, messageDeduplicationID *string) map[string]string {
 	return map[string]string{
@@ -391,7 +414,14 @@ func (s *MessageBrokerSubscriber) checkMessages(sqsSvc *sqs.SQS, queueURL *strin
 						ReceiptHandle: mess.ReceiptHandle,
 					}
 				} else {
-					err = s.handler(bodyMessage)
+					messageAttributes, _ := (*value)["MessageAttributes"].(map[string]interface{})
+
+					now := time.Now()
+					err = s.handle(messageAttributes, bodyMessage)
+
+					if s.metrics != nil {
+						s.metrics.ObserveConsume(s.subscriberID, time.Since(now), err)
+					}
 
 					if err == nil {
 						processedReceiptHandles[i] = &sqs.DeleteMessageBatchRequestEntry{
@@ -415,6 +445,50 @@ func (s *MessageBrokerSubscriber) checkMessages(sqsSvc *sqs.SQS, queueURL *strin
 
 }
 
+// handle calls the handler within a consumer span continuing the producer
+// trace and with the producer request id, or a new one
+func (s *MessageBrokerSubscriber) handle(messageAttributes map[string]interface{}, message interface{}) error {
+	ctx := Propagator.Extract(context.Background(), snsNotificationCarrier(messageAttributes))
+
+	if requestID := snsNotificationCarrier(messageAttributes).Get(RequestIDAttribute); requestID != "" {
+		ctx = ContextWithRequestID(ctx, requestID)
+	} else {
+		ctx = GenerateNewRequestID(ctx)
+	}
+
+	ctx, span := tracer().Start(ctx, fmt.Sprintf("%s process", s.subscriberID),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("sqs"),
+			semconv.MessagingDestinationKey.String(s.subscriberID),
+			semconv.MessagingOperationProcess,
+		))
+	defer span.End()
+
+	var err error
+	if s.ctxHandler != nil {
+		err = s.ctxHandler(ctx, message)
+	} else {
+		// the handler cannot receive the request id, log it to correlate
+		// the handler logs with the producer
+		entry := logrus.WithField("request_id", GetRequestID(ctx)).
+			WithField("subscriber", s.subscriberID)
+		entry.Info("handling message")
+
+		err = s.handler(message)
+		if err != nil {
+			entry.WithError(err).Error("error handling message")
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
 /*
 func (s *MessageBrokerSubscriber) parseMessagePayload(message *sqs.Message) (map[string]interface{}, error) {
 	var payload map[string]interface{}
@@ -0,0 +1,101 @@
+package grok
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// PeerCertificateSubject is the context key holding the verified client
+	// certificate subject
+	PeerCertificateSubject = "peer_certificate_subject"
+)
+
+// TLSSettings ...
+type TLSSettings struct {
+	CertFile          string `yaml:"cert_file"`
+	KeyFile           string `yaml:"key_file"`
+	KeyPassphrase     string `yaml:"key_passphrase"`
+	ClientCAFile      string `yaml:"client_ca_file"`
+	RequireClientCert bool   `yaml:"require_client_cert"`
+}
+
+// NewTLSConfig builds a server tls.Config from settings. When a client CA is
+// configured client certificates are verified against it and, if
+// RequireClientCert is set, required.
+func NewTLSConfig(settings *TLSSettings) (*tls.Config, error) {
+	cert, err := ioutil.ReadFile(settings.CertFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate %s: %v", settings.CertFile, err)
+	}
+
+	key, err := ioutil.ReadFile(settings.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key %s: %v", settings.KeyFile, err)
+	}
+
+	certificate, err := LoadCertificate(cert, key, settings.KeyPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{*certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if settings.ClientCAFile != "" {
+		ca, err := ioutil.ReadFile(settings.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client ca %s: %v", settings.ClientCAFile, err)
+		}
+
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in client ca %s", settings.ClientCAFile)
+		}
+
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if settings.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if settings.RequireClientCert {
+		return nil, fmt.Errorf("client_ca_file is required when require_client_cert is set")
+	}
+
+	return config, nil
+}
+
+// PeerCertificateMiddleware exposes the verified client certificate subject
+// through PeerCertificateSubject
+func PeerCertificateMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 && len(c.Request.TLS.VerifiedChains[0]) > 0 {
+			c.Set(PeerCertificateSubject, c.Request.TLS.VerifiedChains[0][0].Subject.String())
+		}
+
+		c.Next()
+	}
+}
+
+// PeerCertificate returns the verified client certificate of a grpc call
+func PeerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return info.State.VerifiedChains[0][0]
+}
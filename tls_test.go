@@ -0,0 +1,161 @@
+package grok_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TLSTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	dir        string
+	caPool     *x509.CertPool
+	clientCert tls.Certificate
+	settings   *grok.TLSSettings
+}
+
+func TestTLSTestSuite(t *testing.T) {
+	suite.Run(t, new(TLSTestSuite))
+}
+
+func (s *TLSTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.dir = s.T().TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "grok test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	s.assert.NoError(err)
+	ca, _ := x509.ParseCertificate(caDER)
+
+	s.caPool = x509.NewCertPool()
+	s.caPool.AddCert(ca)
+
+	serverCert, serverKey := s.issue(ca, caKey, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := s.issue(ca, caKey, "partner", x509.ExtKeyUsageClientAuth)
+
+	s.write("ca.pem", "CERTIFICATE", caDER)
+	s.write("server.pem", "CERTIFICATE", serverCert)
+	s.write("server.key", "PRIVATE KEY", serverKey)
+
+	s.clientCert, err = tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: clientKey}))
+	s.assert.NoError(err)
+
+	s.settings = &grok.TLSSettings{
+		CertFile:          filepath.Join(s.dir, "server.pem"),
+		KeyFile:           filepath.Join(s.dir, "server.key"),
+		ClientCAFile:      filepath.Join(s.dir, "ca.pem"),
+		RequireClientCert: true,
+	}
+}
+
+func (s *TLSTestSuite) issue(ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	s.assert.NoError(err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	s.assert.NoError(err)
+	return der, keyDER
+}
+
+func (s *TLSTestSuite) write(name string, kind string, der []byte) {
+	err := ioutil.WriteFile(filepath.Join(s.dir, name), pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600)
+	s.assert.NoError(err)
+}
+
+func (s *TLSTestSuite) TestMutualTLS() {
+	config, err := grok.NewTLSConfig(s.settings)
+	s.assert.NoError(err)
+	s.assert.Equal(tls.RequireAndVerifyClientCert, config.ClientAuth)
+
+	server := grok.New(
+		grok.WithSettings(&grok.Settings{API: &grok.APISettings{TLS: s.settings}}),
+		grok.WithContainer(&testContainer{}))
+	server.Engine.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(grok.PeerCertificateSubject))
+	})
+
+	ts := httptest.NewUnstartedServer(server.Engine)
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      s.caPool,
+		Certificates: []tls.Certificate{s.clientCert},
+	}}}
+
+	resp, err := client.Get(ts.URL + "/whoami")
+	s.assert.NoError(err)
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	s.assert.Equal(http.StatusOK, resp.StatusCode)
+	s.assert.Equal("CN=partner", string(body))
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs: s.caPool,
+	}}}
+
+	_, err = anonymous.Get(ts.URL + "/whoami")
+	s.assert.Error(err)
+}
+
+func (s *TLSTestSuite) TestRequireClientCertWithoutCA() {
+	s.settings.ClientCAFile = ""
+
+	_, err := grok.NewTLSConfig(s.settings)
+	s.assert.Error(err)
+}
+
+func (s *TLSTestSuite) TestNewGRPCServer() {
+	server, err := grok.NewGRPCServer(&grok.Settings{GRPC: &grok.GRPCSettings{TLS: s.settings}})
+	s.assert.NoError(err)
+	s.assert.NotNil(server)
+
+	_, err = grok.NewGRPCServer(&grok.Settings{GRPC: &grok.GRPCSettings{TLS: &grok.TLSSettings{CertFile: "missing.pem"}}})
+	s.assert.Error(err)
+}
+
+func (s *TLSTestSuite) TestLoadCertificateInvalidKey() {
+	_, err := grok.LoadCertificate([]byte("invalid"), []byte("invalid"), "")
+	s.assert.Error(err)
+}
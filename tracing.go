@@ -0,0 +1,341 @@
+package grok
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TracerName ...
+	TracerName = "github.com/contbank/grok"
+	// TraceparentHeader ...
+	TraceparentHeader = "traceparent"
+)
+
+var (
+	// Propagator handles W3C traceparent/tracestate headers
+	Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+)
+
+// TracingSettings ...
+type TracingSettings struct {
+	ServiceName string `yaml:"service_name"`
+	Exporter    string `yaml:"exporter"` // stdout, file or none
+	File        string `yaml:"file"`
+}
+
+// CreateSpanExporter ...
+func CreateSpanExporter(settings *TracingSettings) (sdktrace.SpanExporter, error) {
+	switch settings.Exporter {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		file, err := os.OpenFile(settings.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(file))
+	case "", "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %s", settings.Exporter)
+	}
+}
+
+// CreateTracerProvider ...
+func CreateTracerProvider(settings *TracingSettings) (*sdktrace.TracerProvider, error) {
+	exporter, err := CreateSpanExporter(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTracerProvider(settings.ServiceName, exporter), nil
+}
+
+// NewTracerProvider creates a provider exporting to exporter and installs it
+// as the global tracer provider
+func NewTracerProvider(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	}
+
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator)
+
+	return provider
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// TracingMiddleware extracts the incoming traceparent and starts a server span
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := Propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		name := c.FullPath()
+		if name == "" {
+			name = c.Request.URL.Path
+		}
+
+		ctx, span := tracer().Start(ctx, fmt.Sprintf("%s %s", c.Request.Method, name),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(c.Request.Method),
+				semconv.HTTPRouteKey.String(c.FullPath()),
+				semconv.HTTPTargetKey.String(c.Request.URL.Path),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		Propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// tracingTransport starts a client span for outbound requests and injects
+// traceparent into the request headers
+func tracingTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx, span := tracer().Start(req.Context(), fmt.Sprintf("HTTP %s", req.Method),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(req.Method),
+				semconv.HTTPURLKey.String(req.URL.String()),
+			))
+		defer span.End()
+
+		req = req.Clone(ctx)
+		Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+		resp, err := next.RoundTrip(req)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return resp, err
+		}
+
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+
+		return resp, err
+	})
+}
+
+// TracingUnaryServerInterceptor ...
+func TracingUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startGRPCSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return resp, err
+	}
+}
+
+// TracingStreamServerInterceptor ...
+func TracingStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startGRPCSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	}
+}
+
+func startGRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = Propagator.Extract(ctx, metadataCarrier(md))
+
+	return tracer().Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("grpc"),
+			attribute.String("rpc.method", fullMethod),
+		))
+}
+
+// contextServerStream overrides the context of a grpc.ServerStream
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier adapts grpc metadata to propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key string, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// TraceID returns the trace id of the span in ctx, if any
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// snsAttributesCarrier adapts SNS message attributes to propagation.TextMapCarrier
+type snsAttributesCarrier map[string]*sns.MessageAttributeValue
+
+func (m snsAttributesCarrier) Get(key string) string {
+	if value, ok := m[key]; ok && value.StringValue != nil {
+		return *value.StringValue
+	}
+	return ""
+}
+
+func (m snsAttributesCarrier) Set(key string, value string) {
+	m[key] = &sns.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+func (m snsAttributesCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// snsNotificationCarrier reads the message attributes of an SNS notification
+// delivered to SQS
+type snsNotificationCarrier map[string]interface{}
+
+func (m snsNotificationCarrier) Get(key string) string {
+	attribute, ok := m[key].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := attribute["Value"].(string)
+	return value
+}
+
+func (m snsNotificationCarrier) Set(key string, value string) {
+	m[key] = map[string]interface{}{"Type": "String", "Value": value}
+}
+
+func (m snsNotificationCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MongoTracingMonitor creates a span for every command sent to MongoDB
+func MongoTracingMonitor() *event.CommandMonitor {
+	spans := new(sync.Map)
+
+	end := func(requestID int64, err error) {
+		value, ok := spans.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			_, span := tracer().Start(ctx, fmt.Sprintf("mongo.%s", evt.CommandName),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemMongoDB,
+					semconv.DBNameKey.String(evt.DatabaseName),
+					semconv.DBOperationKey.String(evt.CommandName),
+				))
+			spans.Store(evt.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			end(evt.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			end(evt.RequestID, errors.New(evt.Failure))
+		},
+	}
+}
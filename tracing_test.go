@@ -0,0 +1,132 @@
+package grok_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	exporter *tracetest.InMemoryExporter
+	provider *trace.TracerProvider
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+func (s *TracingTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.exporter = tracetest.NewInMemoryExporter()
+	s.provider = grok.NewTracerProvider("grok-test", s.exporter)
+}
+
+func (s *TracingTestSuite) spanNames() []string {
+	s.provider.ForceFlush(context.Background())
+
+	names := []string{}
+	for _, span := range s.exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func (s *TracingTestSuite) TestHTTPPropagation() {
+	var upstreamTraceparent string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get(grok.TraceparentHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	authorize := grok.NewInternalAuthorize(&grok.InternalAuth{
+		URLs: []*string{grok.String(upstream.URL)},
+	})
+
+	engine := gin.New()
+	engine.Use(grok.TracingMiddleware())
+	engine.GET("/customers/:id", authorize.PermissionRequired("read:customers"), func(c *gin.Context) {
+		s.assert.Equal(testTraceID, grok.TraceID(c.Request.Context()))
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/customers/1", nil)
+	req.Header.Set(grok.TraceparentHeader, testTraceparent)
+	req.Header.Set(grok.CurrentIdentityHeader, "12345678909")
+	response := httptest.NewRecorder()
+
+	engine.ServeHTTP(response, req)
+
+	s.assert.Equal(http.StatusOK, response.Code)
+	s.assert.True(strings.HasPrefix(upstreamTraceparent, "00-"+testTraceID))
+	s.assert.True(strings.HasPrefix(response.Header().Get(grok.TraceparentHeader), "00-"+testTraceID))
+	s.assert.Contains(s.spanNames(), "GET /customers/:id")
+	s.assert.Contains(s.spanNames(), "HTTP POST")
+}
+
+func (s *TracingTestSuite) TestGRPCPropagation() {
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(grok.TraceparentHeader, testTraceparent))
+
+	interceptor := grok.TracingUnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/grok.Test/Ping"}
+
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		s.assert.Equal(testTraceID, grok.TraceID(ctx))
+		return nil, nil
+	})
+
+	s.assert.NoError(err)
+	s.assert.Contains(s.spanNames(), "grok.Test/Ping")
+}
+
+func (s *TracingTestSuite) TestMongoMonitor() {
+	monitor := grok.MongoTracingMonitor()
+
+	monitor.Started(context.Background(), &event.CommandStartedEvent{
+		CommandName:  "find",
+		DatabaseName: "grok",
+		RequestID:    1,
+	})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 1},
+	})
+
+	s.assert.Contains(s.spanNames(), "mongo.find")
+}
+
+func (s *TracingTestSuite) TestCreateSpanExporter() {
+	exporter, err := grok.CreateSpanExporter(&grok.TracingSettings{Exporter: "none"})
+	s.assert.NoError(err)
+	s.assert.Nil(exporter)
+
+	exporter, err = grok.CreateSpanExporter(&grok.TracingSettings{
+		Exporter: "file",
+		File:     s.T().TempDir() + "/spans.json",
+	})
+	s.assert.NoError(err)
+	s.assert.NotNil(exporter)
+
+	_, err = grok.CreateSpanExporter(&grok.TracingSettings{Exporter: "zipkin"})
+	s.assert.Error(err)
+}
@@ -77,7 +77,7 @@ func (a *InternalTransactionalToken) Validate() gin.HandlerFunc {
 		}
 
 		// passwords api
-		req, err := http.NewRequest("POST", a.settings.URL, bytes.NewReader(b))
+		req, err := http.NewRequestWithContext(c.Request.Context(), "POST", a.settings.URL, bytes.NewReader(b))
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, defaultError)
 			return
@@ -91,7 +91,7 @@ func (a *InternalTransactionalToken) Validate() gin.HandlerFunc {
 			req.Header.Set("X-Current-Identity", *currentIdentity)
 		}
 
-		client := http.Client{}
+		client := newHTTPClient("transactional_token")
 		resp, err := client.Do(req)
 
 		if err != nil {
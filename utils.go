@@ -456,8 +456,12 @@ func FormatCNPJOrCPF(document string, returnType bool) string {
 // GenerateNewRequestID ...
 func GenerateNewRequestID(ctx context.Context) context.Context {
 	requestID := uuid.New().String()
-	ctx = context.WithValue(ctx, "Request-Id", requestID)
-	return ctx
+	return ContextWithRequestID(ctx, requestID)
+}
+
+// ContextWithRequestID stores the request id read by GetRequestID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, "Request-Id", requestID)
 }
 
 // GenerateNewWorkerRequestID ...
@@ -29,6 +29,8 @@ func NewValidator() *validator.Validate {
 	validate.RegisterValidation("phonecellphone", PhoneOrCellphone())
 	validate.RegisterValidation("fullname", FullName)
 
+	registerTranslations(validate)
+
 	return validate
 }
 
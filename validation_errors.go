@@ -0,0 +1,346 @@
+package grok
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	pt_BR_translations "github.com/go-playground/validator/v10/translations/pt_BR"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LanguageEN ...
+	LanguageEN = "en"
+	// LanguagePTBR ...
+	LanguagePTBR = "pt_BR"
+)
+
+var (
+	// DefaultLanguage is used when Accept-Language has no supported language
+	DefaultLanguage = LanguageEN
+
+	translator = ut.New(en.New(), en.New(), pt_BR.New())
+
+	// translators are shared by every validator, see registerTranslations
+	translators = map[string]*sharedTranslator{
+		LanguageEN:   newSharedTranslator(LanguageEN),
+		LanguagePTBR: newSharedTranslator(LanguagePTBR),
+	}
+	translationsOnce sync.Once
+
+	// customTagMessages are the messages of the grok validation tags
+	customTagMessages = map[string]map[string]string{
+		LanguageEN: {
+			"objectid":       "{0} must be a valid id",
+			"cpf":            "{0} must be a valid CPF",
+			"cnpj":           "{0} must be a valid CNPJ",
+			"cnpjcpf":        "{0} must be a valid CPF or CNPJ",
+			"phone":          "{0} must be a valid phone number",
+			"cellphone":      "{0} must be a valid cellphone number",
+			"phonecellphone": "{0} must be a valid phone or cellphone number",
+			"fullname":       "{0} must be a full name",
+		},
+		LanguagePTBR: {
+			"objectid":       "{0} deve ser um identificador válido",
+			"cpf":            "{0} deve ser um CPF válido",
+			"cnpj":           "{0} deve ser um CNPJ válido",
+			"cnpjcpf":        "{0} deve ser um CPF ou CNPJ válido",
+			"phone":          "{0} deve ser um telefone válido",
+			"cellphone":      "{0} deve ser um celular válido",
+			"phonecellphone": "{0} deve ser um telefone ou celular válido",
+			"fullname":       "{0} deve ser o nome completo",
+		},
+	}
+
+	// sensitiveFields never have their rejected value exposed
+	sensitiveFields = []string{"password", "secret", "token", "pin", "cvv", "document"}
+	sensitiveTags   = []string{"cpf", "cnpj", "cnpjcpf"}
+)
+
+// sharedTranslator ignores the messages added again once the first
+// validator registered them. The translation functions are kept per
+// validator, so every validator registers the messages, but they are only
+// written to the shared translator once.
+type sharedTranslator struct {
+	ut.Translator
+	registered bool
+}
+
+func newSharedTranslator(language string) *sharedTranslator {
+	trans, _ := translator.GetTranslator(language)
+	return &sharedTranslator{Translator: trans}
+}
+
+// Add ...
+func (t *sharedTranslator) Add(key interface{}, text string, override bool) error {
+	if t.registered {
+		return nil
+	}
+	return t.Translator.Add(key, text, override)
+}
+
+// AddCardinal ...
+func (t *sharedTranslator) AddCardinal(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	if t.registered {
+		return nil
+	}
+	return t.Translator.AddCardinal(key, text, rule, override)
+}
+
+// AddOrdinal ...
+func (t *sharedTranslator) AddOrdinal(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	if t.registered {
+		return nil
+	}
+	return t.Translator.AddOrdinal(key, text, rule, override)
+}
+
+// AddRange ...
+func (t *sharedTranslator) AddRange(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	if t.registered {
+		return nil
+	}
+	return t.Translator.AddRange(key, text, rule, override)
+}
+
+// getTranslator returns the translator of the language, en when it is not
+// supported
+func getTranslator(language string) ut.Translator {
+	if trans, found := translators[language]; found {
+		return trans
+	}
+	return translators[LanguageEN]
+}
+
+// registerTranslations registers the en and pt_BR messages of the default
+// and grok tags. The first call happens when Validator is created.
+func registerTranslations(validate *validator.Validate) {
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		LanguageEN:   en_translations.RegisterDefaultTranslations,
+		LanguagePTBR: pt_BR_translations.RegisterDefaultTranslations,
+	}
+
+	for language, register := range defaults {
+		trans := translators[language]
+
+		if err := register(validate, trans); err != nil {
+			logrus.WithError(err).
+				WithField("language", language).
+				Error("error registering validation translations")
+		}
+
+		for tag, message := range customTagMessages[language] {
+			message := message
+			err := validate.RegisterTranslation(tag, trans,
+				func(ut ut.Translator) error {
+					return ut.Add(tag, message, true)
+				},
+				func(ut ut.Translator, fe validator.FieldError) string {
+					t, _ := ut.T(fe.Tag(), fe.Field())
+					return t
+				})
+
+			if err != nil {
+				logrus.WithError(err).
+					WithField("tag", tag).
+					Error("error registering validation translation")
+			}
+		}
+	}
+
+	translationsOnce.Do(func() {
+		for _, trans := range translators {
+			trans.registered = true
+		}
+	})
+}
+
+// BindingValidator validates the binding tag of gin bindings with the grok
+// validations, json field names and translated messages. It is installed
+// as gin binding.Validator.
+type BindingValidator struct {
+	validate *validator.Validate
+}
+
+// NewBindingValidator ...
+func NewBindingValidator() *BindingValidator {
+	validate := NewValidator()
+	validate.SetTagName("binding")
+	return &BindingValidator{validate: validate}
+}
+
+// ValidateStruct validates structs, pointers to structs and slices of them,
+// the errors of the elements of a slice are joined
+func (v *BindingValidator) ValidateStruct(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		return v.ValidateStruct(value.Elem().Interface())
+	case reflect.Struct:
+		return v.validate.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		result := validator.ValidationErrors{}
+		for i := 0; i < value.Len(); i++ {
+			err := v.ValidateStruct(value.Index(i).Interface())
+			if err == nil {
+				continue
+			}
+			validationErrors, ok := err.(validator.ValidationErrors)
+			if !ok {
+				return err
+			}
+			result = append(result, validationErrors...)
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// Engine ...
+func (v *BindingValidator) Engine() interface{} {
+	return v.validate
+}
+
+var _ binding.StructValidator = &BindingValidator{}
+
+// FromLocalizedValidationErrors converts validation errors into an Error
+// with one message and one FieldError per failure, in the language
+// negotiated from acceptLanguage
+func FromLocalizedValidationErrors(errors error, acceptLanguage string) *Error {
+	validationErrors, ok := errors.(validator.ValidationErrors)
+
+	if !ok {
+		return NewError(0, "cannot parse validation errors")
+	}
+
+	trans := getTranslator(NegotiateLanguage(acceptLanguage))
+
+	err := NewError(http.StatusUnprocessableEntity, "INVALID_PARAMETER")
+	err.Errors = fieldErrors(validationErrors, trans)
+
+	for _, e := range err.Errors {
+		err.Messages = append(err.Messages, e.Message)
+	}
+
+	return err
+}
+
+func fieldErrors(validationErrors validator.ValidationErrors, trans ut.Translator) []FieldError {
+	result := make([]FieldError, 0, len(validationErrors))
+
+	for _, e := range validationErrors {
+		result = append(result, FieldError{
+			Field:   fieldPath(e),
+			Message: e.Translate(trans),
+			Tag:     e.Tag(),
+			Param:   e.Param(),
+			Value:   rejectedValue(e),
+		})
+	}
+
+	return result
+}
+
+// fieldPath removes the struct name from the namespace, e.g.
+// Customer.address.zip_code becomes address.zip_code
+func fieldPath(e validator.FieldError) string {
+	parts := strings.SplitN(e.Namespace(), ".", 2)
+	if len(parts) < 2 {
+		return e.Field()
+	}
+	return parts[1]
+}
+
+func rejectedValue(e validator.FieldError) interface{} {
+	name := strings.ToLower(e.Field())
+
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return redacted
+		}
+	}
+
+	for _, tag := range sensitiveTags {
+		if e.Tag() == tag {
+			return redacted
+		}
+	}
+
+	value := reflect.ValueOf(e.Value())
+	switch value.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return e.Value()
+	default:
+		return nil
+	}
+}
+
+// NegotiateLanguage picks the supported language with the highest quality
+// in an Accept-Language header
+func NegotiateLanguage(acceptLanguage string) string {
+	type candidate struct {
+		language string
+		quality  float64
+	}
+
+	candidates := []candidate{}
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		language := ""
+		switch {
+		case tag == "pt" || strings.HasPrefix(tag, "pt-") || strings.HasPrefix(tag, "pt_"):
+			language = LanguagePTBR
+		case tag == "en" || strings.HasPrefix(tag, "en-") || strings.HasPrefix(tag, "en_"):
+			language = LanguageEN
+		}
+
+		if language != "" && quality > 0 {
+			candidates = append(candidates, candidate{language, quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	return candidates[0].language
+}
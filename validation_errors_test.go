@@ -0,0 +1,115 @@
+package grok_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/contbank/grok"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type validationAddress struct {
+	ZipCode string `json:"zip_code" validate:"required,len=8"`
+}
+
+type validationCustomer struct {
+	Name     string             `json:"name" validate:"fullname"`
+	Document string             `json:"document" validate:"cpf"`
+	Password string             `json:"password" validate:"min=8"`
+	Age      int                `json:"age" validate:"gte=18"`
+	Address  *validationAddress `json:"address" validate:"required"`
+}
+
+func invalidCustomer() error {
+	return grok.Validator.Struct(&validationCustomer{
+		Name:     "John",
+		Document: "12345678900",
+		Password: "123",
+		Age:      10,
+		Address:  &validationAddress{ZipCode: "123"},
+	})
+}
+
+func TestFromLocalizedValidationErrors(t *testing.T) {
+	err := grok.FromLocalizedValidationErrors(invalidCustomer(), "pt-BR,pt;q=0.9,en;q=0.8")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, err.Code)
+	assert.Equal(t, []grok.FieldError{
+		{Field: "name", Message: "name deve ser o nome completo", Tag: "fullname", Value: "John"},
+		{Field: "document", Message: "document deve ser um CPF válido", Tag: "cpf", Value: "[REDACTED]"},
+		{Field: "password", Message: "password deve ter pelo menos 8 caracteres", Tag: "min", Param: "8", Value: "[REDACTED]"},
+		{Field: "age", Message: "age deve ser 18 ou superior", Tag: "gte", Param: "18", Value: 10},
+		{Field: "address.zip_code", Message: "zip_code deve ter 8 caracteres", Tag: "len", Param: "8", Value: "123"},
+	}, err.Errors)
+	assert.Equal(t, "name deve ser o nome completo", err.Messages[0])
+
+	err = grok.FromLocalizedValidationErrors(invalidCustomer(), "en-US")
+	assert.Equal(t, "name must be a full name", err.Errors[0].Message)
+	assert.Equal(t, "zip_code must be 8 characters in length", err.Errors[4].Message)
+
+	legacy := grok.FromValidationErros(invalidCustomer())
+	assert.Equal(t, "name must be a full name", legacy.Messages[0])
+	assert.Equal(t, "name must be a full name", legacy.Errors[0].Message)
+}
+
+func TestBindingErrorTranslated(t *testing.T) {
+	type transfer struct {
+		Amount   int    `json:"amount" binding:"gte=1"`
+		Document string `json:"document" binding:"required,cpf"`
+	}
+
+	engine := gin.New()
+	engine.Use(grok.ProblemJSONMiddleware())
+	engine.POST("/transfers", func(c *gin.Context) {
+		body := new(transfer)
+		if err := c.ShouldBindJSON(body); err != nil {
+			grok.BindingError(c, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest("POST", "/transfers", strings.NewReader(`{"amount":0,"document":"123"}`))
+	req.Header.Set("Accept-Language", "pt-BR")
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, req)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	problem := new(grok.Problem)
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), problem))
+	assert.Equal(t, []grok.FieldError{
+		{Field: "amount", Message: "amount deve ser 1 ou superior", Tag: "gte", Param: "1", Value: float64(0)},
+		{Field: "document", Message: "document deve ser um CPF válido", Tag: "cpf", Value: "[REDACTED]"},
+	}, problem.Errors)
+}
+
+func TestNewValidatorTranslations(t *testing.T) {
+	err := grok.NewValidator().Struct(&validationAddress{ZipCode: "123"})
+
+	assert.Equal(t, "zip_code deve ter 8 caracteres",
+		grok.FromLocalizedValidationErrors(err, "pt-BR").Errors[0].Message)
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	var items = []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", grok.LanguageEN},
+		{"pt-BR", grok.LanguagePTBR},
+		{"pt", grok.LanguagePTBR},
+		{"en-US,en;q=0.9", grok.LanguageEN},
+		{"fr-FR,pt-BR;q=0.8,en;q=0.5", grok.LanguagePTBR},
+		{"pt-BR;q=0.5,en;q=0.9", grok.LanguageEN},
+		{"de-DE", grok.LanguageEN},
+	}
+
+	for _, item := range items {
+		assert.Equal(t, item.expected, grok.NegotiateLanguage(item.acceptLanguage), item.acceptLanguage)
+	}
+}